@@ -0,0 +1,42 @@
+// ginkgo-tracker-notifier processes Ginkgo test-suite reports outside of a
+// running test-suite.
+//
+// Usage:
+//
+//	ginkgo-tracker-notifier replay --report report.json --run-id 123 --config cfg.yaml
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: ginkgo-tracker-notifier <command> [flags]
+
+Commands:
+  replay    process a report produced by ginkgo --json-report
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "replay":
+		err = replay(os.Args[2:])
+	case "-h", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
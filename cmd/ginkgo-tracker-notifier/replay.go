@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/process_result"
+)
+
+// replay processes a report produced by ginkgo --json-report running the
+// same pipeline (Elastic, Jira, Webex and Slack) as process_result.Register.
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	reportFile := fs.String("report", "", "path of the report produced by ginkgo --json-report (required)")
+	runID := fs.Int64("run-id", 0, "run id (required)")
+	configFile := fs.String("config", "", "path of the configuration file (required)")
+	dryRun := fs.Bool("dry-run", false, "log what would be done without modifying any external system")
+	logs := fs.Bool("logs", false, "enable logs")
+	statusFile := fs.String("status-file", "", "if set, outcome of each sink is written to this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *reportFile == "" || *configFile == "" || *runID == 0 {
+		fs.Usage()
+		return fmt.Errorf("--report, --run-id and --config are required")
+	}
+
+	f, err := os.Open(*reportFile)
+	if err != nil {
+		return fmt.Errorf("failed to open report: %w", err)
+	}
+	defer f.Close()
+
+	reports, err := ginkgo_helper.LoadReports(f)
+	if err != nil {
+		return err
+	}
+
+	setters := []process_result.Option{
+		process_result.WithConfigFile(*configFile),
+		process_result.WithRunID(*runID),
+		process_result.WithLogWriter(os.Stdout),
+	}
+	if *logs {
+		setters = append(setters, process_result.WithLogs())
+	}
+	if *dryRun {
+		setters = append(setters, process_result.WithDryRun())
+	}
+	if *statusFile != "" {
+		setters = append(setters, process_result.WithStatusFile(*statusFile))
+	}
+
+	return process_result.ProcessReports(context.Background(), reports, setters...)
+}
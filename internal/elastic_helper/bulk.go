@@ -0,0 +1,133 @@
+package elastic_helper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	elastic "github.com/olivere/elastic/v7"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+)
+
+const (
+	// DefaultBulkSize is the default number of documents sent in a single bulk request
+	DefaultBulkSize = 500
+	// DefaultFlushInterval is the default interval after which pending documents are sent
+	DefaultFlushInterval = 5 * time.Second
+
+	// bulkMaxRetries is the maximum number of times documents rejected with
+	// 429 (Too Many Requests) are retried
+	bulkMaxRetries = 3
+)
+
+// bulkRetryBackoff is the time waited before first retry. It doubles at every retry.
+var bulkRetryBackoff = time.Second
+
+// bulkCollector collects, from bulk responses, documents which failed and
+// documents which must be retried.
+type bulkCollector struct {
+	mu    sync.Mutex
+	retry []elastic.BulkableRequest
+	errs  []error
+}
+
+// after is invoked by BulkProcessor after each bulk request is committed.
+// requests and response.Items are 1 to 1 and in the same order.
+func (c *bulkCollector) after(executionID int64, requests []elastic.BulkableRequest,
+	response *elastic.BulkResponse, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		if elastic.IsStatusCode(err, http.StatusTooManyRequests) {
+			c.retry = append(c.retry, requests...)
+			return
+		}
+		c.errs = append(c.errs, fmt.Errorf("bulk request with %d documents failed: %w", len(requests), err))
+		return
+	}
+
+	if response == nil || !response.Errors {
+		return
+	}
+
+	for i := range response.Items {
+		for _, result := range response.Items[i] {
+			if result.Status == http.StatusTooManyRequests && i < len(requests) {
+				c.retry = append(c.retry, requests[i])
+				continue
+			}
+			if result.Error != nil {
+				c.errs = append(c.errs, fmt.Errorf("failed to store document %s: %s: %s",
+					result.Id, result.Error.Type, result.Error.Reason))
+			}
+		}
+	}
+}
+
+// bulkIndex stores all requests using elastic bulk API.
+// Documents rejected with 429 (Too Many Requests) are retried up to bulkMaxRetries times.
+// Returns an error listing every document which could not be stored.
+func bulkIndex(ctx context.Context, client *elastic.Client, requests []elastic.BulkableRequest,
+	info *ElasticInfo) error {
+	bulkSize := info.BulkSize
+	if bulkSize <= 0 {
+		bulkSize = DefaultBulkSize
+	}
+	flushInterval := info.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval
+	}
+
+	errs := make([]error, 0)
+	backoff := bulkRetryBackoff
+	for attempt := 0; len(requests) > 0; attempt++ {
+		if attempt > 0 {
+			if attempt > bulkMaxRetries {
+				errs = append(errs, fmt.Errorf("%d documents rejected with status %d after %d retries",
+					len(requests), http.StatusTooManyRequests, bulkMaxRetries))
+				break
+			}
+			utils.Byf(fmt.Sprintf("Retrying %d documents rejected with status %d", len(requests), http.StatusTooManyRequests))
+			select {
+			case <-ctx.Done():
+				errs = append(errs, fmt.Errorf("%d documents not stored: %w", len(requests), ctx.Err()))
+				return utils.AggregateErrors(errs)
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		collector := &bulkCollector{}
+		processor, err := client.BulkProcessor().
+			Name("ginkgo-tracker-notifier").
+			Workers(1).
+			BulkActions(bulkSize).
+			FlushInterval(flushInterval).
+			// Retries are handled here so that per document errors are not lost
+			RetryItemStatusCodes().
+			Backoff(elastic.StopBackoff{}).
+			After(collector.after).
+			Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to start bulk processor: %w", err)
+		}
+
+		for i := range requests {
+			processor.Add(requests[i])
+		}
+
+		// Close flushes pending requests
+		if err := processor.Close(); err != nil {
+			utils.Byf(fmt.Sprintf("Bulk processor reported an error: %v", err))
+		}
+
+		errs = append(errs, collector.errs...)
+		requests = collector.retry
+	}
+
+	return utils.AggregateErrors(errs)
+}
@@ -0,0 +1,109 @@
+package elastic_helper_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	elastic "github.com/olivere/elastic/v7"
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/elastic_helper"
+)
+
+func getReport(numTests int) *ginkgoTypes.Report {
+	report := &ginkgoTypes.Report{}
+	for i := 0; i < numTests; i++ {
+		report.SpecReports = append(report.SpecReports, ginkgoTypes.SpecReport{
+			LeafNodeType: ginkgoTypes.NodeTypeIt,
+			LeafNodeText: fmt.Sprintf("test %d", i),
+			State:        ginkgoTypes.SpecStatePassed,
+			NumAttempts:  1,
+			RunTime:      time.Second,
+		})
+	}
+	return report
+}
+
+var _ = Describe("StoreResults", func() {
+	var fake *fakeElastic
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		elastic_helper.SetBulkRetryBackoff(time.Millisecond)
+		fake = newFakeElastic("e2e")
+		server = httptest.NewServer(fake)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("stores all results using bulk requests of configured size", func() {
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", BulkSize: 4}
+		Expect(elastic_helper.StoreResults(getReport(10), 33, info, false)).To(Succeed())
+		Expect(fake.stored).To(HaveLen(10))
+		Expect(fake.bulkCalls).To(Equal(3))
+		Expect(fake.stored).To(HaveKey("run_33_test_test_0"))
+		Expect(fake.stored["run_33_test_test_0"]["result"]).To(Equal("passed"))
+	})
+
+	It("retries documents rejected with 429", func() {
+		fake.bulkStatus = func(id string, call int) int {
+			if call == 1 && strings.HasSuffix(id, "_1") {
+				return http.StatusTooManyRequests
+			}
+			return http.StatusCreated
+		}
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e"}
+		Expect(elastic_helper.StoreResults(getReport(3), 34, info, false)).To(Succeed())
+		Expect(fake.stored).To(HaveLen(3))
+		Expect(fake.bulkCalls).To(Equal(2))
+	})
+
+	It("reports documents which could not be stored", func() {
+		fake.bulkStatus = func(id string, call int) int {
+			if strings.HasSuffix(id, "_2") {
+				return http.StatusBadRequest
+			}
+			return http.StatusCreated
+		}
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e"}
+		err := elastic_helper.StoreResults(getReport(3), 35, info, false)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("run_35_test_test_2"))
+		Expect(err.Error()).To(ContainSubstring("mapper_parsing_exception"))
+		Expect(fake.stored).To(HaveLen(2))
+	})
+
+	It("does not store anything in dry run", func() {
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e"}
+		Expect(elastic_helper.StoreResults(getReport(3), 36, info, true)).To(Succeed())
+		Expect(fake.bulkCalls).To(Equal(0))
+	})
+	It("stops retrying when context is cancelled", func() {
+		elastic_helper.SetBulkRetryBackoff(time.Minute)
+		fake.bulkStatus = func(id string, call int) int {
+			return http.StatusTooManyRequests
+		}
+		client, err := elastic.NewClient(elastic.SetURL(server.URL), elastic.SetSniff(false),
+			elastic.SetHealthcheck(false))
+		Expect(err).ToNot(HaveOccurred())
+		requests := []elastic.BulkableRequest{
+			elastic.NewBulkIndexRequest().Index("e2e").Id("run_37_test_test_0").Doc(map[string]interface{}{"run": 37}),
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err = elastic_helper.BulkIndex(ctx, client, requests, &elastic_helper.ElasticInfo{Index: "e2e"})
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(time.Since(start)).To(BeNumerically("<", 10*time.Second))
+		Expect(fake.bulkCalls).To(Equal(1))
+	})
+})
@@ -15,10 +15,22 @@ import (
 	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
 )
 
+// ElasticInfo contains the elastic DB configuration. It is exposed as process_result.ElasticInfo
+// and query.ElasticInfo.
 type ElasticInfo struct {
-	URL    string // elastic DB URL
-	Index  string // elastic DB Index
-	DryRun bool   // indicates if this is a dryRun
+	URL           string        // elastic DB URL
+	Index         string        // elastic DB Index. Can contain a date placeholder, e.g. e2e-results-{yyyy.MM}
+	BulkSize      int           // number of results sent in a single bulk request. Default to 500
+	FlushInterval time.Duration // interval after which pending results are sent. Default to 5s
+	// CreateIndex, when set, creates Index (if it does not exist) with an explicit mapping.
+	// An Index created with an older schema version is migrated.
+	CreateIndex bool
+	// MaxOutputSize is the maximum size, in bytes, of the GinkgoWriter output stored
+	// for each test. Default to 16KB.
+	MaxOutputSize int
+	// SummaryIndex, when set, is the elastic DB Index where a summary document is stored
+	// for each run. Can contain a date placeholder, e.g. e2e-runs-{yyyy.MM}
+	SummaryIndex string
 }
 
 type ElasticResult struct {
@@ -30,24 +42,72 @@ type ElasticResult struct {
 	Maintainer string `json:"maintainer"`
 	// DurationInMinutes is the duration of the test in minutes
 	DurationInMinutes float64 `json:"durationInMinutes"`
-	// Duration is the duration of the test in seconds
+	// DurationSeconds is the duration of the test in seconds
+	DurationSeconds float64 `json:"durationSeconds"`
+	// DurationInSecond is the duration of the test, rounded to the second, in nanoseconds.
+	// Deprecated: use DurationSeconds. Written until next schema version, so that consumers
+	// can migrate.
 	DurationInSecond time.Duration `json:"durationInSeconds"`
-	// Result indicates whether test passed or failed or it was skipped
+	// Result indicates whether test passed, failed (or panicked, was interrupted or
+	// aborted) or it was skipped. Flaky tests are passed, see Flaky
 	Result string `json:"result"`
+	// Flaky indicates whether test passed after failing at least once
+	Flaky bool `json:"flaky"`
+	// Attempts is the number of times the test was run
+	Attempts int `json:"attempts"`
+	// FailedAttempts is the number of attempts which failed
+	FailedAttempts int `json:"failedAttempts"`
 	// Run is the sanity run id
 	Run int64 `json:"run"`
 	// StartTime is the time test started
 	StartTime time.Time `json:"startTime"`
 	// Serial indicates whether test was run in serial
 	Serial bool `json:"serial"`
+	// FailureMessage is the failure message
+	FailureMessage string `json:"failureMessage,omitempty"`
+	// FailureLocation is the location (file:line) of the failure
+	FailureLocation string `json:"failureLocation,omitempty"`
+	// FailureNodeType is the type of the node which failed (It, BeforeEach, etc.)
+	FailureNodeType string `json:"failureNodeType,omitempty"`
+	// ContainerHierarchy contains the texts of the containers the test is in
+	ContainerHierarchy []string `json:"containerHierarchy,omitempty"`
+	// Labels contains all test labels
+	Labels []string `json:"labels,omitempty"`
+	// ParallelProcess is the ginkgo parallel process the test ran on
+	ParallelProcess int `json:"parallelProcess"`
+	// CapturedOutput is the GinkgoWriter output, truncated to MaxOutputSize
+	CapturedOutput string `json:"capturedOutput,omitempty"`
+	// ReportEntries contains the entries added with AddReportEntry
+	ReportEntries []ElasticReportEntry `json:"reportEntries,omitempty"`
+	// SchemaVersion is the version of this schema
+	SchemaVersion int `json:"schemaVersion"`
+}
+
+// ElasticReportEntry is a report entry added with AddReportEntry
+type ElasticReportEntry struct {
+	// Name is the report entry name
+	Name string `json:"name"`
+	// Value is the string representation of the report entry value
+	Value string `json:"value,omitempty"`
+	// Location is the location (file:line) of the AddReportEntry call
+	Location string `json:"location,omitempty"`
+	// Time is the time the entry was added
+	Time time.Time `json:"time"`
 }
 
 const (
 	healthCheckInterval = 10 * time.Second
+
+	// DefaultMaxOutputSize is the default maximum size, in bytes, of stored GinkgoWriter output
+	DefaultMaxOutputSize = 16 * 1024
+
+	// byStepEntryName is the name of report entries added by ginkgo By. Those are not stored.
+	byStepEntryName = "By Step"
 )
 
-// VerifyInfo verifies provided info (elastic DB and Index) are correct
-func VerifyInfo(ctx context.Context, info *ElasticInfo) error {
+// VerifyInfo verifies provided info (elastic DB and Index) are correct.
+// On dryRun, indices are not created nor migrated.
+func VerifyInfo(ctx context.Context, info *ElasticInfo, dryRun bool) error {
 	if info == nil {
 		return fmt.Errorf("VerifyInfo passed nil pointer")
 	}
@@ -64,16 +124,12 @@ func VerifyInfo(ctx context.Context, info *ElasticInfo) error {
 		return fmt.Errorf("%s", msg)
 	}
 
-	exist, err := client.IndexExists(info.Index).Do(ctx)
-	if err != nil {
-		msg := fmt.Sprintf("Failed to check index %s existence err: %v", info.Index, err)
-		utils.Byf(msg)
-		return fmt.Errorf("%s", msg)
+	if err := prepareIndex(ctx, client, info.Index, getResultProperties(), info, dryRun); err != nil {
+		return err
 	}
-	if !exist {
-		msg := fmt.Sprintf("Index %s does not exist", info.Index)
-		utils.Byf(msg)
-		return fmt.Errorf("%s", msg)
+
+	if info.SummaryIndex != "" {
+		return prepareIndex(ctx, client, info.SummaryIndex, getSummaryProperties(), info, dryRun)
 	}
 
 	return nil
@@ -81,9 +137,11 @@ func VerifyInfo(ctx context.Context, info *ElasticInfo) error {
 
 // StoreResults store test results in elastic db.
 // - report is the list of tests
-// - buildID is current run id
-// - buildEnvironment is current run environment
-func StoreResults(report *ginkgoTypes.Report, runID int64, info *ElasticInfo) {
+// - runID is current run id
+// - dryRun indicates if this is a dryRun. Results are then only logged
+// If info.SummaryIndex is set, a RunSummary document is also stored.
+// Returns an error if any result could not be stored.
+func StoreResults(report *ginkgoTypes.Report, runID int64, info *ElasticInfo, dryRun bool) error {
 	ctx := context.TODO()
 
 	client, err := elastic.NewClient(
@@ -93,62 +151,92 @@ func StoreResults(report *ginkgoTypes.Report, runID int64, info *ElasticInfo) {
 	)
 	if err != nil {
 		utils.Byf(fmt.Sprintf("Failed to create client to access es: %v", err))
-		return
+		return fmt.Errorf("failed to create client to access es: %w", err)
 	}
 
-	exist, err := client.IndexExists(info.Index).Do(ctx)
-	if err != nil {
-		utils.Byf(fmt.Sprintf("Failed to check index %s existence err: %v", info.Index, err))
-		return
-	}
-	if !exist {
-		utils.Byf(fmt.Sprintf("Index %s does not exist", info.Index))
-		return
+	if err := prepareIndex(ctx, client, info.Index, getResultProperties(), info, dryRun); err != nil {
+		return err
 	}
 
-	utils.Byf(fmt.Sprintf("Found %d tests", len(report.SpecReports)))
+	index := resolveIndex(info.Index, report.StartTime)
+	utils.Byf(fmt.Sprintf("Found %d tests. Storing results in index %s", len(report.SpecReports), index))
 
+	requests := make([]elastic.BulkableRequest, 0, len(report.SpecReports))
 	for i := range report.SpecReports {
 		testReport := report.SpecReports[i]
 
-		storeResult(testReport, client, info.Index, runID, info.DryRun)
+		id, r := getResult(&testReport, runID, info)
+		if r == nil {
+			continue
+		}
+
+		if dryRun {
+			utils.Byf("Run ID: %d Store ElasticResult %s", runID, render.AsCode(r))
+			continue
+		}
+
+		requests = append(requests, elastic.NewBulkIndexRequest().Index(index).Id(id).Doc(r))
+	}
+
+	errs := make([]error, 0)
+	if len(requests) != 0 {
+		utils.Byf(fmt.Sprintf("Storing %d results", len(requests)))
+		if err := bulkIndex(ctx, client, requests, info); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if info.SummaryIndex != "" {
+		if err := storeSummary(ctx, client, report, runID, info, dryRun); err != nil {
+			errs = append(errs, err)
+		}
 	}
+
+	return utils.AggregateErrors(errs)
 }
 
-// GetFailuresForRun returns failed test for a given run <buildEnvironment, buildID>
-func GetFailuresForRun(buildID int, buildEnvironment, esURL, index string) (*elastic.SearchResult, error) {
-	const maxResult = 200
-	client, err := elastic.NewClient(
-		elastic.SetSniff(false),
-		elastic.SetURL(esURL),
-		elastic.SetHealthcheckInterval(healthCheckInterval),
-	)
-	if err != nil {
-		utils.Byf(fmt.Sprintf("Failed to create client to access es: %v", err))
-		return nil, err
+// prepareIndex validates index and:
+// - if CreateIndex is set, makes sure index exists with the expected mapping;
+// - otherwise verifies index exists. Indices matching a pattern are created by elastic
+// when first document is stored, so their existence is not verified.
+func prepareIndex(ctx context.Context, client *elastic.Client, index string,
+	properties map[string]interface{}, info *ElasticInfo, dryRun bool) error {
+	if err := validateIndex(index); err != nil {
+		utils.Byf(err.Error())
+		return err
 	}
 
-	generalQ := elastic.NewBoolQuery().Should()
+	if info.CreateIndex {
+		if err := ensureIndex(ctx, client, index, properties, dryRun); err != nil {
+			utils.Byf(err.Error())
+			return err
+		}
+		return nil
+	}
 
-	// Filter by failed test
-	generalQ.Filter(elastic.NewMatchQuery("result", ginkgoTypes.SpecStateFailed.String()))
-	// Filter by run
-	generalQ.Filter(elastic.NewMatchQuery("run", fmt.Sprintf("%d", buildID)))
+	if isIndexPattern(index) {
+		return nil
+	}
 
-	searchResult, err := client.Search().Index(index).Query(generalQ).Size(maxResult).
-		Pretty(true).            // pretty print request and response JSON
-		Do(context.Background()) // execute
+	exist, err := client.IndexExists(index).Do(ctx)
 	if err != nil {
-		utils.Byf(fmt.Sprintf("Failed to run query %v", err))
-		return nil, err
+		msg := fmt.Sprintf("Failed to check index %s existence err: %v", index, err)
+		utils.Byf(msg)
+		return fmt.Errorf("%s", msg)
+	}
+	if !exist {
+		msg := fmt.Sprintf("Index %s does not exist", index)
+		utils.Byf(msg)
+		return fmt.Errorf("%s", msg)
 	}
 
-	return searchResult, nil
+	return nil
 }
 
-func storeResult(testReport ginkgoTypes.SpecReport, client *elastic.Client, index string,
-	runID int64, dryRun bool) {
-	testName, maintainer := ginkgo_helper.GetTestNameAndMaintainer(&testReport)
+// getResult returns the document to store for a test along with its id.
+// Returns nil if no document should be stored for the test.
+func getResult(testReport *ginkgoTypes.SpecReport, runID int64, info *ElasticInfo) (string, *ElasticResult) {
+	testName, maintainer := ginkgo_helper.GetTestNameAndMaintainer(testReport)
 
 	// E2E runs in parallel. GINKGO_NODES defines how many nodes.
 	// That means there are multiple SynchronizedAfterSuite and SynchronizedAfterSuite
@@ -157,33 +245,63 @@ func storeResult(testReport ginkgoTypes.SpecReport, client *elastic.Client, inde
 	if testName == ginkgoTypes.NodeTypeSynchronizedBeforeSuite.String() ||
 		testName == ginkgoTypes.NodeTypeSynchronizedAfterSuite.String() {
 		if testReport.ParallelProcess != 1 {
-			return
+			return "", nil
 		}
 	}
 
 	r := ElasticResult{
 		Name: testName,
 		// Description is what allows us to find from a query in es for a failed test, the corresponding Jira bug
-		Description:       ginkgo_helper.GetSummary(&testReport),
-		DurationInMinutes: testReport.RunTime.Minutes(),
-		DurationInSecond:  testReport.RunTime.Round(time.Second),
-		Run:               runID,
-		Maintainer:        maintainer,
-		StartTime:         testReport.StartTime,
-		Serial:            ginkgo_helper.IsTestSerial(&testReport),
+		Description:        ginkgo_helper.GetSummary(testReport),
+		DurationInMinutes:  testReport.RunTime.Minutes(),
+		DurationSeconds:    testReport.RunTime.Seconds(),
+		DurationInSecond:   testReport.RunTime.Round(time.Second),
+		Run:                runID,
+		Maintainer:         maintainer,
+		StartTime:          testReport.StartTime,
+		Serial:             ginkgo_helper.IsTestSerial(testReport),
+		Flaky:              ginkgo_helper.IsFlaky(testReport),
+		Attempts:           testReport.NumAttempts,
+		FailedAttempts:     ginkgo_helper.GetFailedAttempts(testReport),
+		ContainerHierarchy: testReport.ContainerHierarchyTexts,
+		Labels:             testReport.Labels(),
+		ParallelProcess:    testReport.ParallelProcess,
+		ReportEntries:      getReportEntries(testReport),
+		SchemaVersion:      SchemaVersion,
 	}
 	r.Result = testReport.State.String()
 
-	runInfo := fmt.Sprintf("run_%d_test_%s", runID, strings.TrimSpace(testName))
-	if dryRun {
-		utils.Byf("Run ID: %d Store ElasticResult %s", runID, render.AsCode(r))
-		return
+	if testReport.Failed() {
+		r.FailureMessage = testReport.Failure.Message
+		r.FailureLocation = testReport.Failure.Location.String()
+		r.FailureNodeType = testReport.Failure.FailureNodeType.String()
 	}
 
-	_, err := client.Index().Index(index).Id(runInfo).BodyJson(r).Do(context.TODO())
-	if err != nil {
-		utils.Byf(fmt.Sprintf("Failed to store result %s. Result %s", testName, r.Result))
-	} else {
-		utils.Byf(fmt.Sprintf("Stored result %s. Result %s", testName, r.Result))
+	maxOutputSize := info.MaxOutputSize
+	if maxOutputSize <= 0 {
+		maxOutputSize = DefaultMaxOutputSize
 	}
+	r.CapturedOutput = utils.TruncateHead(testReport.CapturedGinkgoWriterOutput, maxOutputSize)
+
+	return fmt.Sprintf("run_%d_test_%s", runID, strings.TrimSpace(testName)), &r
+}
+
+// getReportEntries returns the entries added with AddReportEntry, excluding the
+// ones added by ginkgo By
+func getReportEntries(testReport *ginkgoTypes.SpecReport) []ElasticReportEntry {
+	entries := make([]ElasticReportEntry, 0)
+	for i := range testReport.ReportEntries {
+		entry := &testReport.ReportEntries[i]
+		if entry.Name == byStepEntryName {
+			continue
+		}
+		entries = append(entries, ElasticReportEntry{
+			Name:     entry.Name,
+			Value:    entry.StringRepresentation(),
+			Location: entry.Location.String(),
+			Time:     entry.Time,
+		})
+	}
+
+	return entries
 }
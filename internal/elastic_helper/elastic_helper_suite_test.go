@@ -0,0 +1,13 @@
+package elastic_helper_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestElasticHelper(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ElasticHelper Suite")
+}
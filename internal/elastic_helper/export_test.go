@@ -0,0 +1,22 @@
+package elastic_helper
+
+import "time"
+
+func SetBulkRetryBackoff(backoff time.Duration) {
+	bulkRetryBackoff = backoff
+}
+
+var (
+	IsIndexPattern  = isIndexPattern
+	ValidateIndex   = validateIndex
+	ResolveIndex    = resolveIndex
+	SearchIndex     = searchIndex
+	GetTemplateName = getTemplateName
+	GetSummaryID    = getSummaryID
+	BuildHistories  = buildHistories
+	BulkIndex       = bulkIndex
+)
+
+func SetScrollSize(size int) {
+	scrollSize = size
+}
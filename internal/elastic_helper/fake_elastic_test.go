@@ -0,0 +1,356 @@
+package elastic_helper_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/gomega"
+)
+
+// fakeElastic is a minimal elastic server. bulkStatus, if set, returns the
+// status of a document given its id and the bulk request number.
+type fakeElastic struct {
+	mu          sync.Mutex
+	bulkCalls   int
+	stored      map[string]map[string]interface{}
+	bulkStatus  func(id string, call int) int
+	mappings    map[string]map[string]interface{}
+	putMappings int
+	templates   map[string]map[string]interface{}
+	indexOf     map[string]string        // document id -> index
+	scrolls     map[string][]interface{} // scroll id -> hits not returned yet
+	scrollSizes map[string]int           // scroll id -> page size
+	scrollCalls int
+}
+
+// newFakeElastic returns a fakeElastic where indices exist with an empty mapping
+func newFakeElastic(indices ...string) *fakeElastic {
+	f := &fakeElastic{
+		stored:      make(map[string]map[string]interface{}),
+		mappings:    make(map[string]map[string]interface{}),
+		templates:   make(map[string]map[string]interface{}),
+		indexOf:     make(map[string]string),
+		scrolls:     make(map[string][]interface{}),
+		scrollSizes: make(map[string]int),
+	}
+	for i := range indices {
+		f.mappings[indices[i]] = map[string]interface{}{}
+	}
+	return f
+}
+
+func (f *fakeElastic) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/":
+		fmt.Fprint(w, `{"version": {"number": "7.17.0"}}`)
+	case r.URL.Path == "/_bulk":
+		f.bulk(w, r)
+	case r.URL.Path == "/_search/scroll" && r.Method == http.MethodPost:
+		body := map[string]string{}
+		Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		f.scroll(w, body["scroll_id"])
+	case r.URL.Path == "/_search/scroll" && r.Method == http.MethodDelete:
+		fmt.Fprint(w, `{"succeeded": true}`)
+	case len(segments) == 2 && segments[1] == "_search":
+		f.search(w, r, segments[0])
+	case len(segments) == 2 && segments[0] == "_index_template" && r.Method == http.MethodPut:
+		body := map[string]interface{}{}
+		Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		f.templates[segments[1]] = body
+		fmt.Fprint(w, `{"acknowledged": true}`)
+	case len(segments) == 1 && r.Method == http.MethodHead:
+		if _, ok := f.mappings[segments[0]]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case len(segments) == 1 && r.Method == http.MethodPut:
+		body := map[string]map[string]interface{}{}
+		Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		f.mappings[segments[0]] = body["mappings"]
+		fmt.Fprintf(w, `{"acknowledged": true, "index": %q}`, segments[0])
+	case len(segments) >= 2 && segments[1] == "_mapping" && r.Method == http.MethodGet:
+		result := map[string]interface{}{}
+		for _, name := range f.matchIndices(segments[0]) {
+			result[name] = map[string]interface{}{"mappings": f.mappings[name]}
+		}
+		data, err := json.Marshal(result)
+		Expect(err).ToNot(HaveOccurred())
+		_, _ = w.Write(data)
+	case len(segments) == 2 && segments[1] == "_mapping" && r.Method == http.MethodPut:
+		body := map[string]interface{}{}
+		Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		for _, name := range f.matchIndices(segments[0]) {
+			if !f.putMapping(w, name, body) {
+				return
+			}
+		}
+		fmt.Fprint(w, `{"acknowledged": true}`)
+	case len(segments) == 3 && segments[1] == "_doc" && r.Method == http.MethodPut:
+		doc := map[string]interface{}{}
+		Expect(json.NewDecoder(r.Body).Decode(&doc)).To(Succeed())
+		f.stored[segments[2]] = doc
+		f.indexOf[segments[2]] = segments[0]
+		fmt.Fprintf(w, `{"_index": %q, "_id": %q, "result": "created"}`, segments[0], segments[2])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{}`)
+	}
+}
+
+// putMapping merges body into the mapping of index, as elastic does. Changing the type
+// of an existing field is rejected with 400. Returns false if mapping was rejected.
+func (f *fakeElastic) putMapping(w http.ResponseWriter, index string, body map[string]interface{}) bool {
+	mapping := f.mappings[index]
+	if mapping == nil {
+		mapping = map[string]interface{}{}
+	}
+	existing, _ := mapping["properties"].(map[string]interface{})
+	if existing == nil {
+		existing = map[string]interface{}{}
+	}
+
+	properties, _ := body["properties"].(map[string]interface{})
+	for name, property := range properties {
+		current, ok := existing[name].(map[string]interface{})
+		if ok && current["type"] != property.(map[string]interface{})["type"] {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error": {"type": "illegal_argument_exception", "reason": "mapper [%s] cannot be changed from type [%v] to [%v]"}, "status": 400}`,
+				name, current["type"], property.(map[string]interface{})["type"])
+			return false
+		}
+		existing[name] = property
+	}
+	mapping["properties"] = existing
+	if meta, ok := body["_meta"]; ok {
+		mapping["_meta"] = meta
+	}
+	f.mappings[index] = mapping
+	f.putMappings++
+	return true
+}
+
+// matchIndices returns all existing indices matching the comma separated list of
+// index names or wildcard expressions
+func (f *fakeElastic) matchIndices(expression string) []string {
+	matches := make([]string, 0)
+	for _, e := range strings.Split(expression, ",") {
+		for name := range f.mappings {
+			if ok, _ := path.Match(e, name); ok {
+				matches = append(matches, name)
+			}
+		}
+	}
+	return matches
+}
+
+func (f *fakeElastic) bulk(w http.ResponseWriter, r *http.Request) {
+	f.bulkCalls++
+	items := make([]map[string]interface{}, 0)
+	hasErrors := false
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		action := map[string]map[string]string{}
+		Expect(json.Unmarshal(scanner.Bytes(), &action)).To(Succeed())
+		Expect(scanner.Scan()).To(BeTrue())
+		doc := map[string]interface{}{}
+		Expect(json.Unmarshal(scanner.Bytes(), &doc)).To(Succeed())
+
+		id := action["index"]["_id"]
+		status := http.StatusCreated
+		if f.bulkStatus != nil {
+			status = f.bulkStatus(id, f.bulkCalls)
+		}
+		item := map[string]interface{}{"_id": id, "status": status}
+		if status >= http.StatusMultipleChoices {
+			hasErrors = true
+			item["error"] = map[string]string{"type": "mapper_parsing_exception", "reason": "failed to parse"}
+		} else {
+			f.stored[id] = doc
+			f.indexOf[id] = action["index"]["_index"]
+		}
+		items = append(items, map[string]interface{}{"index": item})
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"errors": hasErrors, "items": items})
+	Expect(err).ToNot(HaveOccurred())
+	_, _ = w.Write(data)
+}
+
+// search evaluates bool, term, terms and match queries and terms aggregations
+// (ordered by key desc) over stored documents. When the scroll parameter is set,
+// hits are returned size at a time.
+func (f *fakeElastic) search(w http.ResponseWriter, r *http.Request, index string) {
+	body := map[string]interface{}{}
+	Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+
+	ids := make([]string, 0)
+	for id := range f.stored {
+		for _, e := range strings.Split(index, ",") {
+			if ok, _ := path.Match(e, f.indexOf[id]); ok {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	sort.Strings(ids)
+
+	hits := make([]interface{}, 0)
+	docs := make([]map[string]interface{}, 0)
+	for _, id := range ids {
+		if query, ok := body["query"].(map[string]interface{}); ok && !matchQuery(query, f.stored[id]) {
+			continue
+		}
+		docs = append(docs, f.stored[id])
+		hits = append(hits, map[string]interface{}{"_index": f.indexOf[id], "_id": id, "_source": f.stored[id]})
+	}
+
+	result := map[string]interface{}{}
+	if aggs, ok := body["aggregations"].(map[string]interface{}); ok {
+		result["aggregations"] = getAggregations(aggs, docs)
+	}
+
+	if r.URL.Query().Get("scroll") != "" {
+		size, err := strconv.Atoi(r.URL.Query().Get("size"))
+		Expect(err).ToNot(HaveOccurred())
+		scrollID := fmt.Sprintf("scroll-%d", len(f.scrolls))
+		f.scrolls[scrollID] = hits
+		f.scrollSizes[scrollID] = size
+		f.scrollPage(w, scrollID)
+		return
+	}
+
+	result["hits"] = map[string]interface{}{"total": map[string]interface{}{"value": len(hits)}, "hits": []interface{}{}}
+	data, err := json.Marshal(result)
+	Expect(err).ToNot(HaveOccurred())
+	_, _ = w.Write(data)
+}
+
+func (f *fakeElastic) scroll(w http.ResponseWriter, scrollID string) {
+	f.scrollCalls++
+	f.scrollPage(w, scrollID)
+}
+
+// scrollPage returns the next page of hits for scrollID
+func (f *fakeElastic) scrollPage(w http.ResponseWriter, scrollID string) {
+	size := f.scrollSizes[scrollID]
+	hits := f.scrolls[scrollID]
+	if size < len(hits) {
+		f.scrolls[scrollID] = hits[size:]
+		hits = hits[:size]
+	} else {
+		f.scrolls[scrollID] = nil
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"_scroll_id": scrollID,
+		"hits":       map[string]interface{}{"hits": hits},
+	})
+	Expect(err).ToNot(HaveOccurred())
+	_, _ = w.Write(data)
+}
+
+// matchQuery returns true if doc matches query
+func matchQuery(query map[string]interface{}, doc map[string]interface{}) bool {
+	for kind, value := range query {
+		clause := value.(map[string]interface{})
+		switch kind {
+		case "bool":
+			for _, c := range append(getClauses(clause["filter"]), getClauses(clause["must"])...) {
+				if !matchQuery(c, doc) {
+					return false
+				}
+			}
+			if should := getClauses(clause["should"]); len(should) != 0 {
+				matched := false
+				for _, c := range should {
+					matched = matched || matchQuery(c, doc)
+				}
+				if !matched {
+					return false
+				}
+			}
+		case "term", "match":
+			for field, v := range clause {
+				if m, ok := v.(map[string]interface{}); ok {
+					v = m["value"]
+					if kind == "match" {
+						v = m["query"]
+					}
+				}
+				if fmt.Sprint(doc[field]) != fmt.Sprint(v) {
+					return false
+				}
+			}
+		case "terms":
+			for field, v := range clause {
+				matched := false
+				for _, e := range v.([]interface{}) {
+					matched = matched || fmt.Sprint(doc[field]) == fmt.Sprint(e)
+				}
+				if !matched {
+					return false
+				}
+			}
+		default:
+			Expect(kind).To(BeEmpty(), "unsupported query")
+		}
+	}
+	return true
+}
+
+// getClauses returns the clauses of a bool query occurrence (a single clause or a list)
+func getClauses(value interface{}) []map[string]interface{} {
+	clauses := make([]map[string]interface{}, 0)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		clauses = append(clauses, v)
+	case []interface{}:
+		for i := range v {
+			clauses = append(clauses, v[i].(map[string]interface{}))
+		}
+	}
+	return clauses
+}
+
+// getAggregations evaluates terms aggregations over docs. Buckets are ordered by key desc.
+func getAggregations(aggs map[string]interface{}, docs []map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for name, agg := range aggs {
+		terms := agg.(map[string]interface{})["terms"].(map[string]interface{})
+		field := terms["field"].(string)
+		size := int(terms["size"].(float64))
+
+		counts := map[float64]int{}
+		for i := range docs {
+			counts[docs[i][field].(float64)]++
+		}
+		keys := make([]float64, 0, len(counts))
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		sort.Sort(sort.Reverse(sort.Float64Slice(keys)))
+		if len(keys) > size {
+			keys = keys[:size]
+		}
+
+		buckets := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			buckets = append(buckets, map[string]interface{}{"key": key, "doc_count": counts[key]})
+		}
+		result[name] = map[string]interface{}{"buckets": buckets}
+	}
+	return result
+}
@@ -0,0 +1,145 @@
+package elastic_helper
+
+import (
+	"context"
+	"sort"
+
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+)
+
+// Failed returns true if test failed (failed, panicked, interrupted or aborted)
+func (r *ElasticResult) Failed() bool {
+	return IsFailedResult(r.Result)
+}
+
+// Passed returns true if test passed, at first attempt or after failing (flaky)
+func (r *ElasticResult) Passed() bool {
+	return r.Result == ginkgoTypes.SpecStatePassed.String()
+}
+
+// TestHistory is the history of a test across a window of runs
+type TestHistory struct {
+	// Name is the name of the test
+	Name string
+	// Results contains test results, most recent run first. Runs in the window where
+	// the test was not run are not included.
+	Results []ElasticResult
+}
+
+// FailureStreak returns the number of consecutive runs, starting from the most recent,
+// test failed in. Runs where test was neither passed nor failed (e.g. skipped) are ignored.
+func (h *TestHistory) FailureStreak() int {
+	streak := 0
+	for i := range h.Results {
+		if h.Results[i].Passed() {
+			break
+		}
+		if h.Results[i].Failed() {
+			streak++
+		}
+	}
+	return streak
+}
+
+// FirstFailingRun returns the run current failure streak started with.
+// Returns false if test did not fail in the most recent run it was executed in.
+func (h *TestHistory) FirstFailingRun() (int64, bool) {
+	var run int64
+	found := false
+	for i := range h.Results {
+		if h.Results[i].Passed() {
+			break
+		}
+		if h.Results[i].Failed() {
+			run = h.Results[i].Run
+			found = true
+		}
+	}
+	return run, found
+}
+
+// PassStreak returns the number of consecutive runs, starting from the most recent,
+// test passed in at first attempt. Flaky, failed and skipped runs end the streak.
+func (h *TestHistory) PassStreak() int {
+	streak := 0
+	for i := range h.Results {
+		if !h.Results[i].Passed() || h.Results[i].Flaky {
+			break
+		}
+		streak++
+	}
+	return streak
+}
+
+// PassRate returns the fraction, between 0 and 1, of runs in the window test passed in.
+// Flaky runs count as passed. Runs where test was neither passed nor failed are ignored.
+// Returns 0 if test was never executed in the window.
+func (h *TestHistory) PassRate() float64 {
+	passed, executed := 0, 0
+	for i := range h.Results {
+		if h.Results[i].Passed() {
+			passed++
+			executed++
+		} else if h.Results[i].Failed() {
+			executed++
+		}
+	}
+	if executed == 0 {
+		return 0
+	}
+	return float64(passed) / float64(executed)
+}
+
+// GetPreviousRuns returns the ids of, at most, the lastRuns runs before runID, most
+// recent first. Results for runID itself might have already been stored and are ignored.
+func GetPreviousRuns(ctx context.Context, info *ElasticInfo, runID int64, lastRuns int) ([]int64, error) {
+	runs, err := GetLastRuns(ctx, info, lastRuns+1)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := make([]int64, 0, lastRuns)
+	for i := range runs {
+		if runs[i] < runID && len(previous) < lastRuns {
+			previous = append(previous, runs[i])
+		}
+	}
+	return previous, nil
+}
+
+// GetHistories returns the history, across runs, of every test in testNames or, if
+// testNames is empty, of every test. Histories are sorted by test name.
+func GetHistories(ctx context.Context, info *ElasticInfo, runs []int64, testNames []string) ([]*TestHistory, error) {
+	results, err := GetResults(ctx, info, runs, testNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildHistories(results), nil
+}
+
+// buildHistories groups results by test name. Within each history, results are
+// sorted by run, most recent first. Histories are sorted by test name.
+func buildHistories(results []ElasticResult) []*TestHistory {
+	histories := make(map[string]*TestHistory)
+	for i := range results {
+		name := results[i].Name
+		if _, ok := histories[name]; !ok {
+			histories[name] = &TestHistory{Name: name, Results: []ElasticResult{}}
+		}
+		histories[name].Results = append(histories[name].Results, results[i])
+	}
+
+	sorted := make([]*TestHistory, 0, len(histories))
+	for _, h := range histories {
+		sort.SliceStable(h.Results, func(i, j int) bool {
+			return h.Results[i].Run > h.Results[j].Run
+		})
+		sorted = append(sorted, h)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	return sorted
+}
@@ -0,0 +1,74 @@
+package elastic_helper_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/elastic_helper"
+)
+
+func getHistory(results ...string) *elastic_helper.TestHistory {
+	h := &elastic_helper.TestHistory{Name: "test"}
+	// results are listed most recent first. "flaky" stands for a test which passed after failing
+	for i := range results {
+		r := elastic_helper.ElasticResult{Name: "test", Run: int64(len(results) - i), Result: results[i]}
+		if results[i] == "flaky" {
+			r.Result, r.Flaky = "passed", true
+		}
+		h.Results = append(h.Results, r)
+	}
+	return h
+}
+
+var _ = Describe("TestHistory", func() {
+	It("FailureStreak counts consecutive failures from most recent run", func() {
+		Expect(getHistory("failed", "panicked", "passed", "failed").FailureStreak()).To(Equal(2))
+		Expect(getHistory("passed", "failed").FailureStreak()).To(Equal(0))
+		Expect(getHistory("flaky", "failed").FailureStreak()).To(Equal(0))
+		Expect(getHistory().FailureStreak()).To(Equal(0))
+	})
+
+	It("FailureStreak ignores skipped runs", func() {
+		Expect(getHistory("failed", "skipped", "failed", "passed").FailureStreak()).To(Equal(2))
+	})
+
+	It("FirstFailingRun returns the run current failure streak started with", func() {
+		run, ok := getHistory("failed", "skipped", "failed", "passed").FirstFailingRun()
+		Expect(ok).To(BeTrue())
+		Expect(run).To(BeEquivalentTo(2))
+
+		_, ok = getHistory("passed", "failed").FirstFailingRun()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("PassStreak counts consecutive first attempt passes from most recent run", func() {
+		Expect(getHistory("passed", "passed", "failed", "passed").PassStreak()).To(Equal(2))
+		Expect(getHistory("passed", "flaky", "passed").PassStreak()).To(Equal(1))
+		Expect(getHistory("passed", "skipped", "passed").PassStreak()).To(Equal(1))
+		Expect(getHistory().PassStreak()).To(Equal(0))
+	})
+
+	It("PassRate considers flaky runs as passed and ignores skipped ones", func() {
+		Expect(getHistory("passed", "flaky", "failed", "skipped", "failed").PassRate()).To(Equal(0.5))
+		Expect(getHistory("skipped").PassRate()).To(Equal(0.0))
+		Expect(getHistory("passed").PassRate()).To(Equal(1.0))
+	})
+})
+
+var _ = Describe("BuildHistories", func() {
+	It("groups results by test, most recent run first", func() {
+		histories := elastic_helper.BuildHistories([]elastic_helper.ElasticResult{
+			{Name: "b", Run: 1, Result: "passed"},
+			{Name: "a", Run: 1, Result: "passed"},
+			{Name: "a", Run: 3, Result: "failed", FailureMessage: "boom"},
+			{Name: "a", Run: 2, Result: "failed"},
+		})
+		Expect(histories).To(HaveLen(2))
+		Expect(histories[0].Name).To(Equal("a"))
+		Expect(histories[0].Results).To(HaveLen(3))
+		Expect(histories[0].Results[0].Run).To(BeEquivalentTo(3))
+		Expect(histories[0].Results[0].FailureMessage).To(Equal("boom"))
+		Expect(histories[0].Results[2].Run).To(BeEquivalentTo(1))
+		Expect(histories[1].Name).To(Equal("b"))
+	})
+})
@@ -0,0 +1,281 @@
+package elastic_helper
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	elastic "github.com/olivere/elastic/v7"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+)
+
+// SchemaVersion is the version of the ElasticResult and RunSummary schemas. It is
+// stored in every document and in the index mapping metadata.
+// When ElasticResult (or RunSummary) changes:
+// - bump SchemaVersion;
+// - update getResultProperties (or getSummaryProperties).
+// Indices created with a previous version (or without this package, version 0) are
+// migrated at next run by adding the missing fields to their mapping. Changing the type of an existing field cannot be done
+// in place: it requires creating a new index and reindexing existing documents.
+const SchemaVersion = 3
+
+const schemaVersionKey = "schemaVersion"
+
+// getResultProperties returns the mapping properties for ElasticResult
+func getResultProperties() map[string]interface{} {
+	return map[string]interface{}{
+		"name":              map[string]interface{}{"type": "keyword"},
+		"description":       keywordText(),
+		"maintainer":        map[string]interface{}{"type": "keyword"},
+		"durationInMinutes": map[string]interface{}{"type": "double"},
+		// Deprecated in schema version 3 (holds nanoseconds), see durationSeconds
+		"durationInSeconds": map[string]interface{}{"type": "long"},
+		"result":            map[string]interface{}{"type": "keyword"},
+		"flaky":             map[string]interface{}{"type": "boolean"},
+		"attempts":          map[string]interface{}{"type": "integer"},
+		"failedAttempts":    map[string]interface{}{"type": "integer"},
+		"run":               map[string]interface{}{"type": "long"},
+		"startTime":         map[string]interface{}{"type": "date"},
+		"serial":            map[string]interface{}{"type": "boolean"},
+		// Added in schema version 2
+		"failureMessage":     map[string]interface{}{"type": "text"},
+		"failureLocation":    map[string]interface{}{"type": "keyword"},
+		"failureNodeType":    map[string]interface{}{"type": "keyword"},
+		"containerHierarchy": keywordText(),
+		"labels":             map[string]interface{}{"type": "keyword"},
+		"parallelProcess":    map[string]interface{}{"type": "integer"},
+		"capturedOutput":     map[string]interface{}{"type": "text"},
+		"reportEntries": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"name":     map[string]interface{}{"type": "keyword"},
+				"value":    map[string]interface{}{"type": "text"},
+				"location": map[string]interface{}{"type": "keyword"},
+				"time":     map[string]interface{}{"type": "date"},
+			},
+		},
+		// Added in schema version 3
+		"durationSeconds": map[string]interface{}{"type": "double"},
+		schemaVersionKey:  map[string]interface{}{"type": "integer"},
+	}
+}
+
+// getSummaryProperties returns the mapping properties for RunSummary
+func getSummaryProperties() map[string]interface{} {
+	return map[string]interface{}{
+		"run":                        map[string]interface{}{"type": "long"},
+		"suitePath":                  map[string]interface{}{"type": "keyword"},
+		"suiteDescription":           keywordText(),
+		"suiteSucceeded":             map[string]interface{}{"type": "boolean"},
+		"startTime":                  map[string]interface{}{"type": "date"},
+		"endTime":                    map[string]interface{}{"type": "date"},
+		"durationInSeconds":          map[string]interface{}{"type": "double"},
+		"total":                      map[string]interface{}{"type": "integer"},
+		"passed":                     map[string]interface{}{"type": "integer"},
+		"failed":                     map[string]interface{}{"type": "integer"},
+		"skipped":                    map[string]interface{}{"type": "integer"},
+		"pending":                    map[string]interface{}{"type": "integer"},
+		"flaky":                      map[string]interface{}{"type": "integer"},
+		"suiteLabels":                map[string]interface{}{"type": "keyword"},
+		"specialSuiteFailureReasons": map[string]interface{}{"type": "text"},
+		"hostname":                   map[string]interface{}{"type": "keyword"},
+		"gitSHA":                     map[string]interface{}{"type": "keyword"},
+		schemaVersionKey:             map[string]interface{}{"type": "integer"},
+	}
+}
+
+// getMapping returns the mapping for an index given its properties
+func getMapping(properties map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"_meta":      map[string]interface{}{schemaVersionKey: SchemaVersion},
+		"properties": properties,
+	}
+}
+
+// keywordText returns the mapping for a text field which can also be
+// used for exact match, sorting and aggregations
+func keywordText() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "text",
+		"fields": map[string]interface{}{
+			"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 1024},
+		},
+	}
+}
+
+// ensureIndex makes sure index exists and its mapping is at SchemaVersion.
+// - If index is a pattern (see resolveIndex), an index template is created (or updated) so
+// indices are created with the ElasticResult mapping;
+// - If index does not exist, it is created with the ElasticResult mapping;
+// - If index (or any index matching the pattern) was created with an older SchemaVersion,
+// its mapping is migrated.
+func ensureIndex(ctx context.Context, client *elastic.Client, index string,
+	properties map[string]interface{}, dryRun bool) error {
+	if isIndexPattern(index) {
+		if err := putIndexTemplate(ctx, client, index, properties, dryRun); err != nil {
+			return err
+		}
+	} else {
+		exist, err := client.IndexExists(index).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check index %s existence: %w", index, err)
+		}
+
+		if !exist {
+			if dryRun {
+				utils.Byf(fmt.Sprintf("Create index %s with schema version %d", index, SchemaVersion))
+				return nil
+			}
+			utils.Byf(fmt.Sprintf("Creating index %s with schema version %d", index, SchemaVersion))
+			_, err = client.CreateIndex(index).BodyJson(map[string]interface{}{"mappings": getMapping(properties)}).Do(ctx)
+			if err != nil && !isIndexAlreadyExists(err) {
+				return fmt.Errorf("failed to create index %s: %w", index, err)
+			}
+			return nil
+		}
+	}
+
+	return migrateIndices(ctx, client, searchIndex(index), properties, dryRun)
+}
+
+// putIndexTemplate creates, or updates, the index template applied to all indices
+// matching the pattern
+func putIndexTemplate(ctx context.Context, client *elastic.Client, pattern string,
+	properties map[string]interface{}, dryRun bool) error {
+	name := getTemplateName(pattern)
+	if dryRun {
+		utils.Byf(fmt.Sprintf("Put index template %s with schema version %d", name, SchemaVersion))
+		return nil
+	}
+
+	template := map[string]interface{}{
+		"index_patterns": []string{searchIndex(pattern)},
+		"template":       map[string]interface{}{"mappings": getMapping(properties)},
+		"_meta":          map[string]interface{}{schemaVersionKey: SchemaVersion},
+	}
+
+	utils.Byf(fmt.Sprintf("Putting index template %s with schema version %d", name, SchemaVersion))
+	if _, err := client.IndexPutIndexTemplate(name).BodyJson(template).Do(ctx); err != nil {
+		return fmt.Errorf("failed to put index template %s: %w", name, err)
+	}
+	return nil
+}
+
+// migrateIndices updates the mapping of every index (index can contain wildcards)
+// created with a SchemaVersion older than current one.
+// Only fields not mapped yet are added: the type of an existing field cannot be changed
+// in place. This is the case, for instance, of an index created without CreateIndex,
+// whose fields were mapped dynamically by elastic. Such fields are logged, as using the
+// current mapping for them requires reindexing, and left untouched.
+func migrateIndices(ctx context.Context, client *elastic.Client, index string,
+	properties map[string]interface{}, dryRun bool) error {
+	mappings, err := getIndicesMapping(ctx, client, index)
+	if err != nil {
+		return err
+	}
+
+	toMigrate := make([]string, 0)
+	for name := range mappings {
+		if mappings[name].version < SchemaVersion {
+			toMigrate = append(toMigrate, name)
+		}
+	}
+	sort.Strings(toMigrate)
+
+	for _, name := range toMigrate {
+		missing, conflicting := diffProperties(mappings[name].properties, properties)
+		if len(conflicting) != 0 {
+			utils.Byf(fmt.Sprintf("Index %s maps fields %v with a different type. Reindexing is required to use schema version %d mapping for them",
+				name, conflicting, SchemaVersion))
+		}
+
+		if dryRun {
+			utils.Byf(fmt.Sprintf("Migrate index %s to schema version %d", name, SchemaVersion))
+			continue
+		}
+
+		utils.Byf(fmt.Sprintf("Migrating index %s to schema version %d", name, SchemaVersion))
+		if _, err := client.PutMapping().Index(name).BodyJson(getMapping(missing)).Do(ctx); err != nil {
+			return fmt.Errorf("failed to migrate index %s to schema version %d (reindexing might be required): %w",
+				name, SchemaVersion, err)
+		}
+	}
+
+	return nil
+}
+
+// indexMapping is the mapping of an existing index
+type indexMapping struct {
+	// version is the SchemaVersion stored in mapping metadata (0 if index was not
+	// created by this package)
+	version int
+	// properties contains the mapped fields
+	properties map[string]interface{}
+}
+
+// getIndicesMapping returns the mapping of each index matching index
+func getIndicesMapping(ctx context.Context, client *elastic.Client, index string) (map[string]*indexMapping, error) {
+	mappings, err := client.GetMapping().Index(index).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index %s mapping: %w", index, err)
+	}
+
+	result := make(map[string]*indexMapping)
+	for name, m := range mappings {
+		result[name] = &indexMapping{properties: map[string]interface{}{}}
+		m, ok := m.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		mapping, ok := m["mappings"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if properties, ok := mapping["properties"].(map[string]interface{}); ok {
+			result[name].properties = properties
+		}
+		meta, ok := mapping["_meta"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if v, ok := meta[schemaVersionKey].(float64); ok {
+			result[name].version = int(v)
+		}
+	}
+
+	return result, nil
+}
+
+// diffProperties returns the properties not in existing and the (sorted) names of the
+// ones existing maps with a different type
+func diffProperties(existing, properties map[string]interface{}) (missing map[string]interface{}, conflicting []string) {
+	missing = make(map[string]interface{})
+	for name := range properties {
+		current, ok := existing[name]
+		if !ok {
+			missing[name] = properties[name]
+			continue
+		}
+		if getPropertyType(current) != getPropertyType(properties[name]) {
+			conflicting = append(conflicting, name)
+		}
+	}
+	sort.Strings(conflicting)
+	return missing, conflicting
+}
+
+// getPropertyType returns the type of a mapped field. Objects have no explicit type.
+func getPropertyType(property interface{}) string {
+	if p, ok := property.(map[string]interface{}); ok {
+		if t, ok := p["type"].(string); ok {
+			return t
+		}
+	}
+	return ""
+}
+
+// isIndexAlreadyExists returns true if err is caused by index being created concurrently
+func isIndexAlreadyExists(err error) bool {
+	e, ok := err.(*elastic.Error)
+	return ok && e.Details != nil && e.Details.Type == "resource_already_exists_exception"
+}
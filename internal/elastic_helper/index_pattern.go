@@ -0,0 +1,63 @@
+package elastic_helper
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// indexDateRegexp matches the date placeholder of an index pattern, e.g. {yyyy.MM}
+var indexDateRegexp = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// indexDateFormatRegexp matches a valid date placeholder content
+var indexDateFormatRegexp = regexp.MustCompile(`^(yyyy|MM|dd|[.\-_])+$`)
+
+// indexDateReplacer converts a date placeholder content to a Go time layout
+var indexDateReplacer = strings.NewReplacer("yyyy", "2006", "MM", "01", "dd", "02")
+
+// isIndexPattern returns true if index contains a date placeholder, for instance
+// e2e-results-{yyyy.MM}
+func isIndexPattern(index string) bool {
+	return indexDateRegexp.MatchString(index)
+}
+
+// validateIndex verifies the date placeholder, if any, only contains
+// yyyy, MM, dd and separators (. - _)
+func validateIndex(index string) error {
+	matches := indexDateRegexp.FindAllStringSubmatch(index, -1)
+	if len(matches) > 1 {
+		return fmt.Errorf("index %s contains more than one date placeholder", index)
+	}
+	for i := range matches {
+		if !indexDateFormatRegexp.MatchString(matches[i][1]) {
+			return fmt.Errorf("index %s contains invalid date placeholder %s (only yyyy, MM, dd, '.', '-' and '_' are supported)",
+				index, matches[i][0])
+		}
+	}
+	return nil
+}
+
+// resolveIndex returns the name of the index where documents for a run started at
+// t are stored. If index is a pattern, placeholder is replaced by t (in UTC), for
+// instance e2e-results-{yyyy.MM} is resolved to e2e-results-2022.06.
+func resolveIndex(index string, t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return indexDateRegexp.ReplaceAllStringFunc(index, func(placeholder string) string {
+		return t.UTC().Format(indexDateReplacer.Replace(placeholder[1 : len(placeholder)-1]))
+	})
+}
+
+// searchIndex returns the index expression to use for searches. If index is a pattern,
+// placeholder is replaced by a wildcard so all indices matching the pattern are searched.
+func searchIndex(index string) string {
+	return indexDateRegexp.ReplaceAllString(index, "*")
+}
+
+// getTemplateName returns the name of the index template for an index pattern
+func getTemplateName(pattern string) string {
+	name := strings.Trim(indexDateRegexp.ReplaceAllString(pattern, ""), ".-_")
+	return name + "-template"
+}
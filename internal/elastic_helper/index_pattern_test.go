@@ -0,0 +1,87 @@
+package elastic_helper_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/elastic_helper"
+)
+
+var _ = Describe("Index pattern", func() {
+	It("isIndexPattern returns true only when index contains a date placeholder", func() {
+		Expect(elastic_helper.IsIndexPattern("e2e-results")).To(BeFalse())
+		Expect(elastic_helper.IsIndexPattern("e2e-results-{yyyy.MM}")).To(BeTrue())
+	})
+
+	It("validateIndex reports invalid placeholders", func() {
+		Expect(elastic_helper.ValidateIndex("e2e-results")).To(Succeed())
+		Expect(elastic_helper.ValidateIndex("e2e-results-{yyyy.MM.dd}")).To(Succeed())
+		Expect(elastic_helper.ValidateIndex("e2e-results-{yyyy_MM}")).To(Succeed())
+		Expect(elastic_helper.ValidateIndex("e2e-results-{HH}")).ToNot(Succeed())
+		Expect(elastic_helper.ValidateIndex("e2e-{yyyy}-results-{MM}")).ToNot(Succeed())
+	})
+
+	It("resolveIndex replaces date placeholder", func() {
+		t := time.Date(2022, time.June, 3, 23, 0, 0, 0, time.UTC)
+		Expect(elastic_helper.ResolveIndex("e2e-results", t)).To(Equal("e2e-results"))
+		Expect(elastic_helper.ResolveIndex("e2e-results-{yyyy.MM}", t)).To(Equal("e2e-results-2022.06"))
+		Expect(elastic_helper.ResolveIndex("e2e-results-{yyyy-MM-dd}", t)).To(Equal("e2e-results-2022-06-03"))
+	})
+
+	It("searchIndex replaces date placeholder with a wildcard", func() {
+		Expect(elastic_helper.SearchIndex("e2e-results")).To(Equal("e2e-results"))
+		Expect(elastic_helper.SearchIndex("e2e-results-{yyyy.MM}")).To(Equal("e2e-results-*"))
+	})
+
+	It("getTemplateName returns template name", func() {
+		Expect(elastic_helper.GetTemplateName("e2e-results-{yyyy.MM}")).To(Equal("e2e-results-template"))
+	})
+
+	Context("with elastic", func() {
+		var fake *fakeElastic
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			fake = newFakeElastic()
+			server = httptest.NewServer(fake)
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("StoreResults stores results in index resolved from report start time", func() {
+			info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e-results-{yyyy.MM}"}
+			Expect(elastic_helper.VerifyInfo(context.TODO(), info, false)).To(Succeed())
+
+			report := getReport(2)
+			report.StartTime = time.Date(2022, time.March, 10, 0, 0, 0, 0, time.UTC)
+			Expect(elastic_helper.StoreResults(report, 50, info, false)).To(Succeed())
+			Expect(fake.indexOf).To(HaveLen(2))
+			for id := range fake.indexOf {
+				Expect(fake.indexOf[id]).To(Equal("e2e-results-2022.03"))
+			}
+		})
+
+		It("VerifyInfo puts index template and migrates matching indices when CreateIndex is set", func() {
+			fake.mappings["e2e-results-2022.01"] = map[string]interface{}{}
+			fake.mappings["e2e-results-2022.02"] = map[string]interface{}{
+				"_meta": map[string]interface{}{"schemaVersion": elastic_helper.SchemaVersion},
+			}
+			fake.mappings["other"] = map[string]interface{}{}
+
+			info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e-results-{yyyy.MM}", CreateIndex: true}
+			Expect(elastic_helper.VerifyInfo(context.TODO(), info, false)).To(Succeed())
+			Expect(fake.templates).To(HaveKey("e2e-results-template"))
+			Expect(fake.templates["e2e-results-template"]["index_patterns"]).To(ConsistOf("e2e-results-*"))
+			Expect(fake.putMappings).To(Equal(1))
+			Expect(fake.mappings["e2e-results-2022.01"]["_meta"]).To(
+				HaveKeyWithValue("schemaVersion", BeEquivalentTo(elastic_helper.SchemaVersion)))
+			Expect(fake.mappings["other"]).To(BeEmpty())
+		})
+	})
+})
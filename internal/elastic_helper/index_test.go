@@ -0,0 +1,98 @@
+package elastic_helper_test
+
+import (
+	"context"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/elastic_helper"
+)
+
+var _ = Describe("Index", func() {
+	var fake *fakeElastic
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		fake = newFakeElastic()
+		server = httptest.NewServer(fake)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("VerifyInfo reports an error when index does not exist and CreateIndex is not set", func() {
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e"}
+		Expect(elastic_helper.VerifyInfo(context.TODO(), info, false)).ToNot(Succeed())
+		Expect(fake.mappings).To(BeEmpty())
+	})
+
+	It("VerifyInfo creates index with versioned mapping when CreateIndex is set", func() {
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", CreateIndex: true}
+		Expect(elastic_helper.VerifyInfo(context.TODO(), info, false)).To(Succeed())
+		Expect(fake.mappings).To(HaveKey("e2e"))
+		Expect(fake.mappings["e2e"]["_meta"]).To(HaveKeyWithValue("schemaVersion", BeEquivalentTo(elastic_helper.SchemaVersion)))
+		Expect(fake.mappings["e2e"]["properties"]).To(HaveKey("durationSeconds"))
+		Expect(fake.mappings["e2e"]["properties"]).To(HaveKey("durationInSeconds"))
+		Expect(fake.mappings["e2e"]["properties"]).To(HaveKey("startTime"))
+	})
+
+	It("VerifyInfo does not create index in dry run", func() {
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", CreateIndex: true}
+		Expect(elastic_helper.VerifyInfo(context.TODO(), info, true)).To(Succeed())
+		Expect(fake.mappings).To(BeEmpty())
+	})
+
+	It("VerifyInfo migrates mapping of an index created with an older schema version", func() {
+		fake.mappings["e2e"] = map[string]interface{}{"_meta": map[string]interface{}{"schemaVersion": 0}}
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", CreateIndex: true}
+		Expect(elastic_helper.VerifyInfo(context.TODO(), info, false)).To(Succeed())
+		Expect(fake.putMappings).To(Equal(1))
+		Expect(fake.mappings["e2e"]["_meta"]).To(HaveKeyWithValue("schemaVersion", BeEquivalentTo(elastic_helper.SchemaVersion)))
+	})
+
+	It("VerifyInfo adds only missing fields to an index with dynamic mapping", func() {
+		keywordText := map[string]interface{}{
+			"type":   "text",
+			"fields": map[string]interface{}{"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256}},
+		}
+		fake.mappings["e2e"] = map[string]interface{}{
+			"properties": map[string]interface{}{
+				"name":              keywordText,
+				"maintainer":        keywordText,
+				"result":            keywordText,
+				"durationInSeconds": map[string]interface{}{"type": "long"},
+			},
+		}
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", CreateIndex: true}
+		Expect(elastic_helper.VerifyInfo(context.TODO(), info, false)).To(Succeed())
+		Expect(fake.putMappings).To(Equal(1))
+
+		properties := fake.mappings["e2e"]["properties"].(map[string]interface{})
+		Expect(properties["name"]).To(Equal(keywordText))
+		Expect(properties["result"]).To(Equal(keywordText))
+		Expect(properties).To(HaveKey("durationSeconds"))
+		Expect(properties).To(HaveKey("failureMessage"))
+		Expect(fake.mappings["e2e"]["_meta"]).To(HaveKeyWithValue("schemaVersion", BeEquivalentTo(elastic_helper.SchemaVersion)))
+	})
+
+	It("VerifyInfo does not modify mapping of an index at current schema version", func() {
+		fake.mappings["e2e"] = map[string]interface{}{
+			"_meta": map[string]interface{}{"schemaVersion": elastic_helper.SchemaVersion},
+		}
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", CreateIndex: true}
+		Expect(elastic_helper.VerifyInfo(context.TODO(), info, false)).To(Succeed())
+		Expect(fake.putMappings).To(Equal(0))
+	})
+
+	It("StoreResults stores schema version in every document", func() {
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", CreateIndex: true}
+		Expect(elastic_helper.StoreResults(getReport(2), 40, info, false)).To(Succeed())
+		Expect(fake.stored).To(HaveLen(2))
+		for id := range fake.stored {
+			Expect(fake.stored[id]).To(HaveKeyWithValue("schemaVersion", BeEquivalentTo(elastic_helper.SchemaVersion)))
+		}
+	})
+})
@@ -0,0 +1,190 @@
+package elastic_helper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+
+	elastic "github.com/olivere/elastic/v7"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+)
+
+const (
+	// scrollKeepAlive is how long elastic keeps the search context alive between
+	// two scroll requests
+	scrollKeepAlive = "1m"
+
+	// runsAggregation is the name of the aggregation collecting run ids
+	runsAggregation = "runs"
+)
+
+// scrollSize is the number of documents fetched with each scroll request
+var scrollSize = 500
+
+// failedResults contains all results considered failures
+var failedResults = []interface{}{
+	ginkgoTypes.SpecStateFailed.String(),
+	ginkgoTypes.SpecStatePanicked.String(),
+	ginkgoTypes.SpecStateInterrupted.String(),
+	ginkgoTypes.SpecStateAborted.String(),
+}
+
+// IsFailedResult returns true if result is a failure (failed, panicked, interrupted or aborted)
+func IsFailedResult(result string) bool {
+	for i := range failedResults {
+		if failedResults[i] == result {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLastRuns returns the ids of the last lastRuns runs, most recent first.
+// Runs are ordered by run id, which is expected to increase with every run.
+// If index is a pattern, all indices matching the pattern are searched.
+func GetLastRuns(ctx context.Context, info *ElasticInfo, lastRuns int) ([]int64, error) {
+	if lastRuns <= 0 {
+		return nil, fmt.Errorf("number of runs must be positive, got %d", lastRuns)
+	}
+
+	client, err := newClient(info)
+	if err != nil {
+		return nil, err
+	}
+
+	index := searchIndex(info.Index)
+	agg := elastic.NewTermsAggregation().Field("run").Size(lastRuns).OrderByKeyDesc()
+	searchResult, err := client.Search().Index(index).Size(0).
+		Aggregation(runsAggregation, agg).Do(ctx)
+	if err != nil {
+		utils.Byf(fmt.Sprintf("Failed to get last runs from index %s. Error: %v", index, err))
+		return nil, fmt.Errorf("failed to get last runs from index %s: %w", index, err)
+	}
+
+	terms, ok := searchResult.Aggregations.Terms(runsAggregation)
+	if !ok {
+		return []int64{}, nil
+	}
+
+	runs := make([]int64, 0, len(terms.Buckets))
+	for i := range terms.Buckets {
+		key, ok := terms.Buckets[i].Key.(float64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected run id %v", terms.Buckets[i].Key)
+		}
+		runs = append(runs, int64(key))
+	}
+
+	return runs, nil
+}
+
+// GetResults returns the results stored for runs, sorted by run (most recent first) and
+// test name. If testNames is not empty, only results for those tests are returned.
+// If index is a pattern, all indices matching the pattern are searched.
+func GetResults(ctx context.Context, info *ElasticInfo, runs []int64, testNames []string) ([]ElasticResult, error) {
+	if len(runs) == 0 {
+		return []ElasticResult{}, nil
+	}
+
+	runIDs := make([]interface{}, len(runs))
+	for i := range runs {
+		runIDs[i] = runs[i]
+	}
+
+	query := elastic.NewBoolQuery().Filter(elastic.NewTermsQuery("run", runIDs...))
+	if len(testNames) != 0 {
+		query.Filter(getNameQuery(testNames))
+	}
+
+	return searchResults(ctx, info, query)
+}
+
+// GetFailuresForRun returns the results of all tests which failed in run runID,
+// sorted by test name.
+// If index is a pattern, all indices matching the pattern are searched.
+func GetFailuresForRun(ctx context.Context, info *ElasticInfo, runID int64) ([]ElasticResult, error) {
+	query := elastic.NewBoolQuery().Filter(
+		elastic.NewTermQuery("run", runID),
+		elastic.NewTermsQuery("result", failedResults...),
+	)
+
+	return searchResults(ctx, info, query)
+}
+
+// getNameQuery returns a query matching any of testNames. The name field is a keyword
+// when the index is created with CreateIndex, and a text with a keyword sub-field
+// when the index is created by elastic dynamic mapping. Both are matched.
+func getNameQuery(testNames []string) elastic.Query {
+	names := make([]interface{}, len(testNames))
+	for i := range testNames {
+		names[i] = testNames[i]
+	}
+	return elastic.NewBoolQuery().Should(
+		elastic.NewTermsQuery("name", names...),
+		elastic.NewTermsQuery("name.keyword", names...),
+	).MinimumNumberShouldMatch(1)
+}
+
+// searchResults returns all results matching query. Results are fetched, scrollSize at
+// a time, using the scroll API.
+func searchResults(ctx context.Context, info *ElasticInfo, query elastic.Query) ([]ElasticResult, error) {
+	client, err := newClient(info)
+	if err != nil {
+		return nil, err
+	}
+
+	index := searchIndex(info.Index)
+	scroll := client.Scroll(index).Query(query).Size(scrollSize).KeepAlive(scrollKeepAlive)
+	defer func() {
+		_ = scroll.Clear(context.Background())
+	}()
+
+	results := make([]ElasticResult, 0)
+	for {
+		searchResult, err := scroll.Do(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			utils.Byf(fmt.Sprintf("Failed to search index %s. Error: %v", index, err))
+			return nil, fmt.Errorf("failed to search index %s: %w", index, err)
+		}
+
+		for i := range searchResult.Hits.Hits {
+			var r ElasticResult
+			if err := json.Unmarshal(searchResult.Hits.Hits[i].Source, &r); err != nil {
+				return nil, fmt.Errorf("failed to decode document %s: %w", searchResult.Hits.Hits[i].Id, err)
+			}
+			results = append(results, r)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Run != results[j].Run {
+			return results[i].Run > results[j].Run
+		}
+		return results[i].Name < results[j].Name
+	})
+
+	return results, nil
+}
+
+// newClient returns a client to access elastic DB at info.URL
+func newClient(info *ElasticInfo) (*elastic.Client, error) {
+	client, err := elastic.NewClient(
+		elastic.SetSniff(false),
+		elastic.SetURL(info.URL),
+		elastic.SetHealthcheckInterval(healthCheckInterval),
+	)
+	if err != nil {
+		utils.Byf(fmt.Sprintf("Failed to create client to access es: %v", err))
+		return nil, fmt.Errorf("failed to create client to access es: %w", err)
+	}
+
+	return client, nil
+}
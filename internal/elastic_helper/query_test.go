@@ -0,0 +1,137 @@
+package elastic_helper_test
+
+import (
+	"context"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/elastic_helper"
+)
+
+var _ = Describe("Query", func() {
+	var fake *fakeElastic
+	var server *httptest.Server
+	var info *elastic_helper.ElasticInfo
+
+	BeforeEach(func() {
+		fake = newFakeElastic("e2e")
+		server = httptest.NewServer(fake)
+		info = &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e"}
+
+		// run 1: all passed, run 2: test 1 failed, run 3: test 1 panicked and test 2 failed
+		for run := int64(1); run <= 3; run++ {
+			report := getReport(3)
+			if run >= 2 {
+				report.SpecReports[1].State = ginkgoTypes.SpecStateFailed
+			}
+			if run == 3 {
+				report.SpecReports[1].State = ginkgoTypes.SpecStatePanicked
+				report.SpecReports[2].State = ginkgoTypes.SpecStateFailed
+			}
+			Expect(elastic_helper.StoreResults(report, run, info, false)).To(Succeed())
+		}
+	})
+
+	AfterEach(func() {
+		elastic_helper.SetScrollSize(500)
+		server.Close()
+	})
+
+	It("GetLastRuns returns most recent runs first", func() {
+		runs, err := elastic_helper.GetLastRuns(context.TODO(), info, 2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runs).To(Equal([]int64{3, 2}))
+
+		runs, err = elastic_helper.GetLastRuns(context.TODO(), info, 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runs).To(Equal([]int64{3, 2, 1}))
+	})
+
+	It("GetLastRuns reports an error when number of runs is not positive", func() {
+		_, err := elastic_helper.GetLastRuns(context.TODO(), info, 0)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("GetPreviousRuns returns runs before current one", func() {
+		runs, err := elastic_helper.GetPreviousRuns(context.TODO(), info, 3, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runs).To(Equal([]int64{2}))
+
+		runs, err = elastic_helper.GetPreviousRuns(context.TODO(), info, 4, 5)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runs).To(Equal([]int64{3, 2, 1}))
+	})
+
+	It("GetHistories returns history of each test", func() {
+		histories, err := elastic_helper.GetHistories(context.TODO(), info, []int64{3, 2, 1}, []string{"test_1", "test_2"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(histories).To(HaveLen(2))
+		Expect(histories[0].Name).To(Equal("test_1"))
+		Expect(histories[0].FailureStreak()).To(Equal(2))
+		run, ok := histories[1].FirstFailingRun()
+		Expect(ok).To(BeTrue())
+		Expect(run).To(BeEquivalentTo(3))
+	})
+
+	It("GetResults pages through all results", func() {
+		elastic_helper.SetScrollSize(2)
+
+		results, err := elastic_helper.GetResults(context.TODO(), info, []int64{1, 2, 3}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(HaveLen(9))
+		Expect(fake.scrollCalls).To(Equal(5))
+		Expect(results[0].Run).To(BeEquivalentTo(3))
+		Expect(results[0].Name).To(Equal("test_0"))
+		Expect(results[8].Run).To(BeEquivalentTo(1))
+	})
+
+	It("GetResults returns results for a single test", func() {
+		results, err := elastic_helper.GetResults(context.TODO(), info, []int64{2, 3}, []string{"test_1"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(HaveLen(2))
+		Expect(results[0].Run).To(BeEquivalentTo(3))
+		Expect(results[0].Result).To(Equal(ginkgoTypes.SpecStatePanicked.String()))
+		Expect(results[1].Run).To(BeEquivalentTo(2))
+		Expect(results[1].Result).To(Equal(ginkgoTypes.SpecStateFailed.String()))
+	})
+
+	It("GetResults returns results for multiple tests", func() {
+		results, err := elastic_helper.GetResults(context.TODO(), info, []int64{1}, []string{"test_0", "test_2"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(HaveLen(2))
+		Expect(results[0].Name).To(Equal("test_0"))
+		Expect(results[1].Name).To(Equal("test_2"))
+	})
+
+	It("GetFailuresForRun returns failed, panicked, interrupted and aborted tests", func() {
+		failures, err := elastic_helper.GetFailuresForRun(context.TODO(), info, 3)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(failures).To(HaveLen(2))
+		Expect(failures[0].Name).To(Equal("test_1"))
+		Expect(failures[1].Name).To(Equal("test_2"))
+
+		failures, err = elastic_helper.GetFailuresForRun(context.TODO(), info, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(failures).To(BeEmpty())
+	})
+
+	It("searches all indices matching a pattern", func() {
+		info.Index = "e2e-{yyyy.MM}"
+		report := getReport(1)
+		report.SpecReports[0].State = ginkgoTypes.SpecStateFailed
+		Expect(elastic_helper.StoreResults(report, 4, info, false)).To(Succeed())
+
+		failures, err := elastic_helper.GetFailuresForRun(context.TODO(), info, 4)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(failures).To(HaveLen(1))
+	})
+
+	It("IsFailedResult returns true only for failures", func() {
+		Expect(elastic_helper.IsFailedResult(ginkgoTypes.SpecStateFailed.String())).To(BeTrue())
+		Expect(elastic_helper.IsFailedResult(ginkgoTypes.SpecStateAborted.String())).To(BeTrue())
+		Expect(elastic_helper.IsFailedResult(ginkgoTypes.SpecStatePassed.String())).To(BeFalse())
+	})
+})
@@ -0,0 +1,112 @@
+package elastic_helper_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/elastic_helper"
+)
+
+var _ = Describe("ElasticResult", func() {
+	var fake *fakeElastic
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		fake = newFakeElastic("e2e")
+		server = httptest.NewServer(fake)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("contains failure details, hierarchy, labels, output and report entries", func() {
+		report := &ginkgoTypes.Report{
+			SpecReports: ginkgoTypes.SpecReports{
+				{
+					LeafNodeType:               ginkgoTypes.NodeTypeIt,
+					LeafNodeText:               "return ordered list",
+					LeafNodeLabels:             []string{"maintainer:user-a", "slow"},
+					ContainerHierarchyTexts:    []string{"Verify list methods", "Sort"},
+					ContainerHierarchyLabels:   [][]string{{"lists"}, {}},
+					State:                      ginkgoTypes.SpecStateFailed,
+					NumAttempts:                1,
+					RunTime:                    time.Second,
+					ParallelProcess:            3,
+					CapturedGinkgoWriterOutput: strings.Repeat("a", 100) + "last line",
+					Failure: ginkgoTypes.Failure{
+						Message:         "Expected true to be false",
+						Location:        ginkgoTypes.CodeLocation{FileName: "/src/list_test.go", LineNumber: 42},
+						FailureNodeType: ginkgoTypes.NodeTypeBeforeEach,
+					},
+					ReportEntries: ginkgoTypes.ReportEntries{
+						{Name: "By Step", Value: ginkgoTypes.WrapEntryValue("creating cluster")},
+						{
+							Name:     "cluster-dump",
+							Value:    ginkgoTypes.WrapEntryValue("/tmp/dump.tgz"),
+							Location: ginkgoTypes.CodeLocation{FileName: "/src/list_test.go", LineNumber: 30},
+						},
+					},
+				},
+			},
+		}
+
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", MaxOutputSize: 20}
+		Expect(elastic_helper.StoreResults(report, 60, info, false)).To(Succeed())
+		Expect(fake.stored).To(HaveKey("run_60_test_return_ordered_list"))
+
+		doc := fake.stored["run_60_test_return_ordered_list"]
+		Expect(doc["failureMessage"]).To(Equal("Expected true to be false"))
+		Expect(doc["failureLocation"]).To(Equal("/src/list_test.go:42"))
+		Expect(doc["failureNodeType"]).To(Equal("BeforeEach"))
+		Expect(doc["containerHierarchy"]).To(Equal([]interface{}{"Verify list methods", "Sort"}))
+		Expect(doc["labels"]).To(ConsistOf("lists", "maintainer:user-a", "slow"))
+		Expect(doc["parallelProcess"]).To(BeEquivalentTo(3))
+		Expect(doc["capturedOutput"]).To(HavePrefix("...[truncated]"))
+		Expect(doc["capturedOutput"]).To(HaveSuffix("last line"))
+		Expect(doc["capturedOutput"]).To(HaveLen(len("...[truncated]\n") + 20))
+		Expect(doc["reportEntries"]).To(HaveLen(1))
+		entry := doc["reportEntries"].([]interface{})[0].(map[string]interface{})
+		Expect(entry["name"]).To(Equal("cluster-dump"))
+		Expect(entry["value"]).To(Equal("/tmp/dump.tgz"))
+		Expect(entry["location"]).To(Equal("/src/list_test.go:30"))
+	})
+
+	It("does not contain failure details for passed tests", func() {
+		Expect(elastic_helper.StoreResults(getReport(1), 61, &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e"}, false)).To(Succeed())
+		Expect(fake.stored).To(HaveLen(1))
+		for id := range fake.stored {
+			Expect(fake.stored[id]).ToNot(HaveKey("failureMessage"))
+			Expect(fake.stored[id]).ToNot(HaveKey("failureLocation"))
+		}
+	})
+
+	It("stores flaky tests as passed", func() {
+		report := getReport(1)
+		report.SpecReports[0].NumAttempts = 3
+		Expect(elastic_helper.StoreResults(report, 62, &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e"}, false)).To(Succeed())
+		Expect(fake.stored).To(HaveLen(1))
+		for id := range fake.stored {
+			Expect(fake.stored[id]["result"]).To(Equal("passed"))
+			Expect(fake.stored[id]["flaky"]).To(BeTrue())
+			Expect(fake.stored[id]["failedAttempts"]).To(BeEquivalentTo(2))
+		}
+	})
+
+	It("stores duration in seconds", func() {
+		report := getReport(1)
+		report.SpecReports[0].RunTime = 1500 * time.Millisecond
+		Expect(elastic_helper.StoreResults(report, 63, &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e"}, false)).To(Succeed())
+		Expect(fake.stored).To(HaveLen(1))
+		for id := range fake.stored {
+			Expect(fake.stored[id]["durationSeconds"]).To(BeNumerically("==", 1.5))
+			// Deprecated field, still holding nanoseconds
+			Expect(fake.stored[id]["durationInSeconds"]).To(BeEquivalentTo(2 * time.Second))
+		}
+	})
+})
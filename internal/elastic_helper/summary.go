@@ -0,0 +1,149 @@
+package elastic_helper
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"time"
+
+	"github.com/gdexlab/go-render/render"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+
+	elastic "github.com/olivere/elastic/v7"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+)
+
+// gitSHAEnvVars contains the environment variables, in order of preference,
+// the git SHA of the code under test is read from
+var gitSHAEnvVars = []string{"GIT_SHA", "GIT_COMMIT", "GITHUB_SHA", "CI_COMMIT_SHA"}
+
+// RunSummary describes a run. One document is stored per run.
+type RunSummary struct {
+	// Run is the sanity run id
+	Run int64 `json:"run"`
+	// SuitePath is the path of the test-suite
+	SuitePath string `json:"suitePath"`
+	// SuiteDescription is the description passed to RunSpecs
+	SuiteDescription string `json:"suiteDescription"`
+	// SuiteSucceeded indicates whether the test-suite succeeded
+	SuiteSucceeded bool `json:"suiteSucceeded"`
+	// StartTime is the time test-suite started
+	StartTime time.Time `json:"startTime"`
+	// EndTime is the time test-suite ended
+	EndTime time.Time `json:"endTime"`
+	// DurationInSeconds is the test-suite duration in seconds
+	DurationInSeconds float64 `json:"durationInSeconds"`
+	// Total is the number of tests (It)
+	Total int `json:"total"`
+	// Passed is the number of tests which passed at first attempt
+	Passed int `json:"passed"`
+	// Failed is the number of tests which failed
+	Failed int `json:"failed"`
+	// Skipped is the number of tests which were skipped
+	Skipped int `json:"skipped"`
+	// Pending is the number of pending tests
+	Pending int `json:"pending"`
+	// Flaky is the number of tests which passed after failing at least once
+	Flaky int `json:"flaky"`
+	// SuiteLabels are the labels passed to RunSpecs
+	SuiteLabels []string `json:"suiteLabels,omitempty"`
+	// SpecialSuiteFailureReasons contains reasons the test-suite failed not
+	// related to any test (for instance, interrupted)
+	SpecialSuiteFailureReasons []string `json:"specialSuiteFailureReasons,omitempty"`
+	// Hostname is the host the test-suite ran on
+	Hostname string `json:"hostname,omitempty"`
+	// GitSHA is the git SHA of the code under test, read from GIT_SHA, GIT_COMMIT,
+	// GITHUB_SHA or CI_COMMIT_SHA environment variables
+	GitSHA string `json:"gitSHA,omitempty"`
+	// SchemaVersion is the version of this schema
+	SchemaVersion int `json:"schemaVersion"`
+}
+
+// getSummary returns the RunSummary for a report
+func getSummary(report *ginkgoTypes.Report, runID int64) *RunSummary {
+	summary := &RunSummary{
+		Run:                        runID,
+		SuitePath:                  report.SuitePath,
+		SuiteDescription:           report.SuiteDescription,
+		SuiteSucceeded:             report.SuiteSucceeded,
+		StartTime:                  report.StartTime,
+		EndTime:                    report.EndTime,
+		DurationInSeconds:          report.RunTime.Seconds(),
+		SuiteLabels:                report.SuiteLabels,
+		SpecialSuiteFailureReasons: report.SpecialSuiteFailureReasons,
+		GitSHA:                     getGitSHA(),
+		SchemaVersion:              SchemaVersion,
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		summary.Hostname = hostname
+	}
+
+	for i := range report.SpecReports {
+		testReport := &report.SpecReports[i]
+		if testReport.LeafNodeType != ginkgoTypes.NodeTypeIt {
+			continue
+		}
+
+		summary.Total++
+		switch {
+		case testReport.Failed():
+			summary.Failed++
+		case ginkgo_helper.IsFlaky(testReport):
+			summary.Flaky++
+		case testReport.State == ginkgoTypes.SpecStatePassed:
+			summary.Passed++
+		case testReport.State == ginkgoTypes.SpecStateSkipped:
+			summary.Skipped++
+		case testReport.State == ginkgoTypes.SpecStatePending:
+			summary.Pending++
+		}
+	}
+
+	return summary
+}
+
+// getGitSHA returns the git SHA of the code under test from environment
+func getGitSHA() string {
+	for i := range gitSHAEnvVars {
+		if sha := os.Getenv(gitSHAEnvVars[i]); sha != "" {
+			return sha
+		}
+	}
+	return ""
+}
+
+// getSummaryID returns the id of the RunSummary document of report. More than one
+// test-suite can run with the same run id, so id contains a hash of the suite path.
+func getSummaryID(report *ginkgoTypes.Report, runID int64) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(report.SuitePath))
+	return fmt.Sprintf("run_%d_summary_%08x", runID, h.Sum32())
+}
+
+// storeSummary stores the RunSummary for a report in info.SummaryIndex
+func storeSummary(ctx context.Context, client *elastic.Client, report *ginkgoTypes.Report,
+	runID int64, info *ElasticInfo, dryRun bool) error {
+	if err := prepareIndex(ctx, client, info.SummaryIndex, getSummaryProperties(), info, dryRun); err != nil {
+		return err
+	}
+
+	summary := getSummary(report, runID)
+	if dryRun {
+		utils.Byf("Run ID: %d Store RunSummary %s", runID, render.AsCode(summary))
+		return nil
+	}
+
+	index := resolveIndex(info.SummaryIndex, report.StartTime)
+	_, err := client.Index().Index(index).Id(getSummaryID(report, runID)).BodyJson(summary).Do(ctx)
+	if err != nil {
+		utils.Byf(fmt.Sprintf("Failed to store summary for run %d. Error: %v", runID, err))
+		return fmt.Errorf("failed to store summary for run %d: %w", runID, err)
+	}
+
+	utils.Byf(fmt.Sprintf("Stored summary for run %d in index %s", runID, index))
+	return nil
+}
@@ -0,0 +1,125 @@
+package elastic_helper_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/elastic_helper"
+)
+
+var _ = Describe("RunSummary", func() {
+	var fake *fakeElastic
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		fake = newFakeElastic("e2e", "e2e-runs")
+		server = httptest.NewServer(fake)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	getSummaryReport := func() *ginkgoTypes.Report {
+		startTime := time.Date(2022, time.March, 10, 8, 0, 0, 0, time.UTC)
+		return &ginkgoTypes.Report{
+			SuitePath:                  "/src/e2e",
+			SuiteDescription:           "E2E Suite",
+			SuiteSucceeded:             false,
+			SuiteLabels:                []string{"nightly"},
+			SpecialSuiteFailureReasons: []string{"Interrupted by User"},
+			StartTime:                  startTime,
+			EndTime:                    startTime.Add(90 * time.Second),
+			RunTime:                    90 * time.Second,
+			SpecReports: ginkgoTypes.SpecReports{
+				{LeafNodeType: ginkgoTypes.NodeTypeIt, LeafNodeText: "a", State: ginkgoTypes.SpecStatePassed, NumAttempts: 1},
+				{LeafNodeType: ginkgoTypes.NodeTypeIt, LeafNodeText: "b", State: ginkgoTypes.SpecStatePassed, NumAttempts: 2},
+				{LeafNodeType: ginkgoTypes.NodeTypeIt, LeafNodeText: "c", State: ginkgoTypes.SpecStateFailed, NumAttempts: 1},
+				{LeafNodeType: ginkgoTypes.NodeTypeIt, LeafNodeText: "d", State: ginkgoTypes.SpecStateSkipped},
+				{LeafNodeType: ginkgoTypes.NodeTypeIt, LeafNodeText: "e", State: ginkgoTypes.SpecStatePending},
+				{LeafNodeType: ginkgoTypes.NodeTypeBeforeSuite, State: ginkgoTypes.SpecStatePassed, NumAttempts: 1},
+			},
+		}
+	}
+
+	It("is stored, for each run, when SummaryIndex is set", func() {
+		GinkgoT().Setenv("GIT_SHA", "")
+		GinkgoT().Setenv("GIT_COMMIT", "")
+		GinkgoT().Setenv("GITHUB_SHA", "0a1b2c3")
+
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", SummaryIndex: "e2e-runs"}
+		Expect(elastic_helper.StoreResults(getSummaryReport(), 70, info, false)).To(Succeed())
+
+		id := elastic_helper.GetSummaryID(getSummaryReport(), 70)
+		Expect(id).To(HavePrefix("run_70_summary_"))
+		Expect(fake.stored).To(HaveKey(id))
+		Expect(fake.indexOf[id]).To(Equal("e2e-runs"))
+
+		doc := fake.stored[id]
+		Expect(doc["run"]).To(BeEquivalentTo(70))
+		Expect(doc["suitePath"]).To(Equal("/src/e2e"))
+		Expect(doc["suiteDescription"]).To(Equal("E2E Suite"))
+		Expect(doc["suiteSucceeded"]).To(BeFalse())
+		Expect(doc["durationInSeconds"]).To(BeEquivalentTo(90))
+		Expect(doc["total"]).To(BeEquivalentTo(5))
+		Expect(doc["passed"]).To(BeEquivalentTo(1))
+		Expect(doc["flaky"]).To(BeEquivalentTo(1))
+		Expect(doc["failed"]).To(BeEquivalentTo(1))
+		Expect(doc["skipped"]).To(BeEquivalentTo(1))
+		Expect(doc["pending"]).To(BeEquivalentTo(1))
+		Expect(doc["suiteLabels"]).To(ConsistOf("nightly"))
+		Expect(doc["specialSuiteFailureReasons"]).To(ConsistOf("Interrupted by User"))
+		Expect(doc["gitSHA"]).To(Equal("0a1b2c3"))
+		Expect(doc["hostname"]).ToNot(BeEmpty())
+		Expect(doc["schemaVersion"]).To(BeEquivalentTo(elastic_helper.SchemaVersion))
+	})
+
+	It("is stored in the index resolved from the suite start time", func() {
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", SummaryIndex: "e2e-runs-{yyyy.MM}"}
+		Expect(elastic_helper.StoreResults(getSummaryReport(), 71, info, false)).To(Succeed())
+		Expect(fake.indexOf[elastic_helper.GetSummaryID(getSummaryReport(), 71)]).To(Equal("e2e-runs-2022.03"))
+	})
+
+	It("is stored for each test-suite of a run", func() {
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", SummaryIndex: "e2e-runs"}
+		report := getSummaryReport()
+		Expect(elastic_helper.StoreResults(report, 73, info, false)).To(Succeed())
+		otherReport := getSummaryReport()
+		otherReport.SuitePath = "/src/upgrade"
+		Expect(elastic_helper.StoreResults(otherReport, 73, info, false)).To(Succeed())
+
+		Expect(fake.stored[elastic_helper.GetSummaryID(report, 73)]["suitePath"]).To(Equal("/src/e2e"))
+		Expect(fake.stored[elastic_helper.GetSummaryID(otherReport, 73)]["suitePath"]).To(Equal("/src/upgrade"))
+	})
+
+	It("is created with its own mapping when CreateIndex is set", func() {
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", SummaryIndex: "e2e-summary", CreateIndex: true}
+		Expect(elastic_helper.VerifyInfo(context.TODO(), info, false)).To(Succeed())
+		Expect(fake.mappings).To(HaveKey("e2e-summary"))
+		properties := fake.mappings["e2e-summary"]["properties"].(map[string]interface{})
+		Expect(properties).To(HaveKey("suiteSucceeded"))
+		Expect(properties).ToNot(HaveKey("failureMessage"))
+	})
+
+	It("is not stored when SummaryIndex is not set", func() {
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e"}
+		Expect(elastic_helper.StoreResults(getSummaryReport(), 72, info, false)).To(Succeed())
+		Expect(fake.stored).ToNot(HaveKey(elastic_helper.GetSummaryID(getSummaryReport(), 72)))
+	})
+
+	It("is not stored on dry run", func() {
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", SummaryIndex: "e2e-runs"}
+		Expect(elastic_helper.StoreResults(getSummaryReport(), 73, info, true)).To(Succeed())
+		Expect(fake.stored).To(BeEmpty())
+	})
+
+	It("reports an error when SummaryIndex does not exist", func() {
+		info := &elastic_helper.ElasticInfo{URL: server.URL, Index: "e2e", SummaryIndex: "missing"}
+		Expect(elastic_helper.VerifyInfo(context.TODO(), info, false)).ToNot(Succeed())
+	})
+})
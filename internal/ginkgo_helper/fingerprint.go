@@ -0,0 +1,87 @@
+package ginkgo_helper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+)
+
+// fingerprintLength is the number of hex characters of a fingerprint
+const fingerprintLength = 16
+
+// callArgsRegexp matches the arguments of a function call in a stack trace,
+// e.g. (0xc000123, 0x2)
+var callArgsRegexp = regexp.MustCompile(`\([^()]*\)$`)
+
+// ignoredFramePrefixes are the prefixes of functions, in a stack trace, which are not
+// part of the test: ginkgo, gomega and Go runtime frames change with their versions
+var ignoredFramePrefixes = []string{"github.com/onsi/ginkgo", "github.com/onsi/gomega", "runtime."}
+
+// GetTestID returns an identifier of a test computed from its container hierarchy
+// and leaf text. It does not change when the test is moved within a file.
+func GetTestID(testReport *ginkgoTypes.SpecReport) string {
+	return hash(getTestKey(testReport))
+}
+
+// GetFingerprint returns the fingerprint of a test failure computed from test container
+// hierarchy, leaf text and normalized failure location (function names only, without
+// arguments, addresses, files and line numbers). It does not change when lines shift
+// nor when ginkgo, gomega or Go runtime frames change.
+func GetFingerprint(testReport *ginkgoTypes.SpecReport) string {
+	return hash(getTestKey(testReport) + "\n" + getNormalizedFailureLocation(testReport))
+}
+
+// getTestKey returns container hierarchy texts and leaf text, one per line
+func getTestKey(testReport *ginkgoTypes.SpecReport) string {
+	texts := append([]string{}, testReport.ContainerHierarchyTexts...)
+	return strings.Join(append(texts, GetSummary(testReport)), "\n")
+}
+
+// getNormalizedFailureLocation returns the names of the functions in the failure
+// stack trace, one per line, ignoring ginkgo, gomega and Go runtime frames. If the
+// stack trace is not available (or only contains ignored frames), the name of the
+// file where the failure happened is returned.
+// A stack trace alternates function lines with (tab indented) file:line lines:
+//
+//	github.com/org/e2e.glob..func1.2(0xc000123)
+//		/src/list_test.go:42 +0x1d
+func getNormalizedFailureLocation(testReport *ginkgoTypes.SpecReport) string {
+	stackTrace := testReport.Failure.Location.FullStackTrace
+	if stackTrace == "" {
+		return testReport.Failure.Location.FileName
+	}
+
+	functions := make([]string, 0)
+	for _, line := range strings.Split(stackTrace, "\n") {
+		if line == "" || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, " ") {
+			continue
+		}
+		function := callArgsRegexp.ReplaceAllString(strings.TrimSpace(line), "")
+		if isIgnoredFrame(function) {
+			continue
+		}
+		functions = append(functions, function)
+	}
+	if len(functions) == 0 {
+		return testReport.Failure.Location.FileName
+	}
+	return strings.Join(functions, "\n")
+}
+
+// isIgnoredFrame returns true if function is a ginkgo, gomega or Go runtime function
+func isIgnoredFrame(function string) bool {
+	for _, prefix := range ignoredFramePrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])[:fingerprintLength]
+}
@@ -0,0 +1,70 @@
+package ginkgo_helper_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
+)
+
+const (
+	stackTrace = "github.com/org/e2e.glob..func1.2(0xc000123, 0x2)\n\t/src/list_test.go:42 +0x1d\n" +
+		"github.com/onsi/ginkgo/v2/internal.(*Suite).runNode(0xc000456)\n\t/ginkgo/internal/suite.go:596 +0x12f\n"
+	shiftedStackTrace = "github.com/org/e2e.glob..func1.2(0xc000789, 0x2)\n\t/src/list_test.go:57 +0x2a\n" +
+		"github.com/onsi/ginkgo/v2/internal.(*Suite).runNode(0xc000abc)\n\t/ginkgo/internal/suite.go:596 +0x12f\n"
+	otherStackTrace = "github.com/org/e2e.verifyOrder(0xc000123)\n\t/src/list_test.go:80 +0x1d\n"
+	// stackTrace after a ginkgo, gomega and Go upgrade
+	upgradedStackTrace = "runtime.gopanic(0xc000123)\n\t/go/src/runtime/panic.go:884 +0x213\n" +
+		"github.com/onsi/gomega/internal.(*Assertion).match(0xc000123)\n\t/gomega/internal/assertion.go:106 +0x1f0\n" +
+		"github.com/org/e2e.glob..func1.2(0xc000123, 0x2)\n\t/src/list_test.go:42 +0x1d\n" +
+		"github.com/onsi/ginkgo/v2/internal.(*Suite).runSpecNode(0xc000456)\n\t/ginkgo/internal/suite.go:612 +0x12f\n" +
+		"runtime.goexit()\n\t/go/src/runtime/asm_amd64.s:1598 +0x1\n"
+)
+
+func getFingerprintReport(hierarchy []string, leafText, stackTrace string) *ginkgoTypes.SpecReport {
+	return &ginkgoTypes.SpecReport{
+		ContainerHierarchyTexts: hierarchy,
+		LeafNodeType:            ginkgoTypes.NodeTypeIt,
+		LeafNodeText:            leafText,
+		State:                   ginkgoTypes.SpecStateFailed,
+		Failure: ginkgoTypes.Failure{
+			Location: ginkgoTypes.CodeLocation{FileName: "/src/list_test.go", LineNumber: 42, FullStackTrace: stackTrace},
+		},
+	}
+}
+
+var _ = Describe("Fingerprint", func() {
+	It("GetFingerprint does not change when line numbers and addresses shift", func() {
+		a := getFingerprintReport([]string{"List"}, "return ordered list", stackTrace)
+		b := getFingerprintReport([]string{"List"}, "return ordered list", shiftedStackTrace)
+		Expect(ginkgo_helper.GetFingerprint(a)).To(Equal(ginkgo_helper.GetFingerprint(b)))
+		Expect(ginkgo_helper.GetFingerprint(a)).To(HaveLen(16))
+	})
+
+	It("GetFingerprint does not change when ginkgo, gomega or runtime frames change", func() {
+		a := getFingerprintReport([]string{"List"}, "return ordered list", stackTrace)
+		b := getFingerprintReport([]string{"List"}, "return ordered list", upgradedStackTrace)
+		Expect(ginkgo_helper.GetFingerprint(a)).To(Equal(ginkgo_helper.GetFingerprint(b)))
+	})
+
+	It("GetFingerprint changes when failure happens in a different function", func() {
+		a := getFingerprintReport([]string{"List"}, "return ordered list", stackTrace)
+		b := getFingerprintReport([]string{"List"}, "return ordered list", otherStackTrace)
+		Expect(ginkgo_helper.GetFingerprint(a)).ToNot(Equal(ginkgo_helper.GetFingerprint(b)))
+	})
+
+	It("GetFingerprint and GetTestID differ for specs sharing leaf text in different containers", func() {
+		a := getFingerprintReport([]string{"List"}, "returns error", stackTrace)
+		b := getFingerprintReport([]string{"Map"}, "returns error", stackTrace)
+		Expect(ginkgo_helper.GetFingerprint(a)).ToNot(Equal(ginkgo_helper.GetFingerprint(b)))
+		Expect(ginkgo_helper.GetTestID(a)).ToNot(Equal(ginkgo_helper.GetTestID(b)))
+	})
+
+	It("GetTestID does not depend on failure", func() {
+		a := getFingerprintReport([]string{"List"}, "return ordered list", stackTrace)
+		b := getFingerprintReport([]string{"List"}, "return ordered list", "")
+		b.State = ginkgoTypes.SpecStatePassed
+		Expect(ginkgo_helper.GetTestID(a)).To(Equal(ginkgo_helper.GetTestID(b)))
+	})
+})
@@ -1,7 +1,10 @@
 package ginkgo_helper
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
 
 	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
@@ -70,6 +73,53 @@ func IsTestSerial(testReport *ginkgoTypes.SpecReport) bool {
 	return testReport.IsSerial || testReport.IsInOrderedContainer
 }
 
+// LoadReports loads the reports contained in a file produced by ginkgo --json-report.
+// Such file contains a list of reports, one per test-suite. A single report is also accepted.
+func LoadReports(r io.Reader) ([]ginkgoTypes.Report, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("report is empty")
+	}
+
+	if data[0] == '[' {
+		reports := make([]ginkgoTypes.Report, 0)
+		if err := json.Unmarshal(data, &reports); err != nil {
+			return nil, fmt.Errorf("failed to parse report: %w", err)
+		}
+		return reports, nil
+	}
+
+	report := ginkgoTypes.Report{}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse report: %w", err)
+	}
+	return []ginkgoTypes.Report{report}, nil
+}
+
+// IsFlaky returns true if a test passed after failing at least once.
+func IsFlaky(testReport *ginkgoTypes.SpecReport) bool {
+	return testReport.State == ginkgoTypes.SpecStatePassed && testReport.NumAttempts > 1
+}
+
+// GetFailedAttempts returns how many attempts of a test failed: all of them for a failed
+// test, all but the last one for a flaky test and none otherwise.
+// Ginkgo only reports the outcome of the last attempt, so details of earlier failed
+// attempts are not available.
+func GetFailedAttempts(testReport *ginkgoTypes.SpecReport) int {
+	if testReport.Failed() {
+		return testReport.NumAttempts
+	}
+	if IsFlaky(testReport) {
+		return testReport.NumAttempts - 1
+	}
+	return 0
+}
+
 // getFailureLocation extracts the name of the method where failure happened
 func getFailureLocation(stackTrace string) string {
 	addressIndex := strings.Index(stackTrace, "0x")
@@ -1,6 +1,7 @@
 package ginkgo_helper_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -118,4 +119,58 @@ var _ = Describe("GinkgoUtils", func() {
 		testName, _ := ginkgo_helper.GetTestNameAndMaintainer(report)
 		Expect(testName).To(Equal(report.LeafNodeType.String()))
 	})
+
+	It("LoadReports loads a list of reports", func() {
+		report := getReport()
+		report.State = ginkgoTypes.SpecStateFailed
+		reports := []ginkgoTypes.Report{
+			{SuiteDescription: "suite a", SpecReports: ginkgoTypes.SpecReports{*report}},
+			{SuiteDescription: "suite b"},
+		}
+		data, err := json.Marshal(reports)
+		Expect(err).ToNot(HaveOccurred())
+
+		loaded, err := ginkgo_helper.LoadReports(strings.NewReader(string(data)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(loaded).To(HaveLen(2))
+		Expect(loaded[0].SuiteDescription).To(Equal("suite a"))
+		Expect(loaded[0].SpecReports).To(HaveLen(1))
+		Expect(loaded[0].SpecReports[0].State).To(Equal(ginkgoTypes.SpecStateFailed))
+		Expect(loaded[0].SpecReports[0].LeafNodeText).To(Equal(report.LeafNodeText))
+	})
+
+	It("LoadReports loads a single report", func() {
+		data, err := json.Marshal(ginkgoTypes.Report{SuiteDescription: "suite a"})
+		Expect(err).ToNot(HaveOccurred())
+
+		loaded, err := ginkgo_helper.LoadReports(strings.NewReader(string(data)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(loaded).To(HaveLen(1))
+		Expect(loaded[0].SuiteDescription).To(Equal("suite a"))
+	})
+
+	It("LoadReports reports an error for invalid content", func() {
+		_, err := ginkgo_helper.LoadReports(strings.NewReader(""))
+		Expect(err).To(HaveOccurred())
+		_, err = ginkgo_helper.LoadReports(strings.NewReader("{invalid"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("IsFlaky returns true for a test which passed after multiple attempts", func() {
+		report := getReport()
+		Expect(ginkgo_helper.IsFlaky(report)).To(BeFalse())
+		report.NumAttempts = 3
+		Expect(ginkgo_helper.IsFlaky(report)).To(BeTrue())
+		report.State = ginkgoTypes.SpecStateFailed
+		Expect(ginkgo_helper.IsFlaky(report)).To(BeFalse())
+	})
+
+	It("GetFailedAttempts returns number of failed attempts", func() {
+		report := getReport()
+		Expect(ginkgo_helper.GetFailedAttempts(report)).To(Equal(0))
+		report.NumAttempts = 3
+		Expect(ginkgo_helper.GetFailedAttempts(report)).To(Equal(2))
+		report.State = ginkgoTypes.SpecStateFailed
+		Expect(ginkgo_helper.GetFailedAttempts(report)).To(Equal(3))
+	})
 })
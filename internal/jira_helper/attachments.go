@@ -0,0 +1,103 @@
+package jira_helper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/andygrunwald/go-jira"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+)
+
+const (
+	ginkgoWriterAttachmentName = "ginkgo-writer-output.txt"
+	stdOutErrAttachmentName    = "stdout-stderr.txt"
+)
+
+// attachment is a file attached to the issue of a failed test
+type attachment struct {
+	name    string
+	content []byte
+}
+
+// getAttachments returns the attachments for a failed test:
+// - if info.AttachOutput is set, captured GinkgoWriter output and stdout/stderr, truncated to
+// their last info.MaxAttachmentSize bytes;
+// - the files whose path is the value of a report entry named as one of info.ArtifactEntries.
+// Files larger than info.MaxAttachmentSize, or which cannot be read, are skipped.
+// Attachment names are prefixed with run id, so attachments of different runs can be told apart.
+func getAttachments(testReport *ginkgoTypes.SpecReport, runID string, info *JiraInfo) []attachment {
+	attachments := make([]attachment, 0)
+	add := func(name string, content []byte) {
+		attachments = append(attachments, attachment{name: fmt.Sprintf("run-%s-%s", runID, name), content: content})
+	}
+
+	if info.AttachOutput {
+		if testReport.CapturedGinkgoWriterOutput != "" {
+			add(ginkgoWriterAttachmentName,
+				[]byte(utils.TruncateHead(testReport.CapturedGinkgoWriterOutput, info.MaxAttachmentSize)))
+		}
+		if testReport.CapturedStdOutErr != "" {
+			add(stdOutErrAttachmentName,
+				[]byte(utils.TruncateHead(testReport.CapturedStdOutErr, info.MaxAttachmentSize)))
+		}
+	}
+
+	artifactEntries := make(map[string]bool)
+	for i := range info.ArtifactEntries {
+		artifactEntries[info.ArtifactEntries[i]] = true
+	}
+	for i := range testReport.ReportEntries {
+		entry := &testReport.ReportEntries[i]
+		if !artifactEntries[entry.Name] {
+			continue
+		}
+		path := entry.StringRepresentation()
+		content, err := readArtifact(path, info.MaxAttachmentSize)
+		if err != nil {
+			utils.Byf(fmt.Sprintf("Skipping artifact %s (report entry %s): %v", path, entry.Name, err))
+			continue
+		}
+		add(filepath.Base(path), content)
+	}
+
+	return attachments
+}
+
+// readArtifact returns the content of the file at path. Returns an error if path is not
+// a regular file or it is larger than maxSize bytes (if positive).
+func readArtifact(path string, maxSize int) ([]byte, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fileInfo.Mode().IsRegular() {
+		return nil, fmt.Errorf("not a regular file")
+	}
+	if maxSize > 0 && fileInfo.Size() > int64(maxSize) {
+		return nil, fmt.Errorf("size %d is larger than %d bytes", fileInfo.Size(), maxSize)
+	}
+	return os.ReadFile(path)
+}
+
+// attachArtifacts attaches the artifacts of a failed test (see getAttachments) to issue
+func attachArtifacts(ctx context.Context, jiraClient *jira.Client, issueID, runID string,
+	testReport *ginkgoTypes.SpecReport, info *JiraInfo) error {
+	errs := make([]error, 0)
+	for _, a := range getAttachments(testReport, runID, info) {
+		if _, resp, err := jiraClient.Issue.PostAttachmentWithContext(ctx, issueID,
+			bytes.NewReader(a.content), a.name); err != nil {
+			utils.Byf(fmt.Sprintf("Failed to attach %s to issue %s. Error: %v. Resp %s",
+				a.name, issueID, err, getResponseBody(resp)))
+			errs = append(errs, fmt.Errorf("failed to attach %s to issue %s: %w", a.name, issueID, err))
+			continue
+		}
+		utils.Byf(fmt.Sprintf("Attached %s to issue %s", a.name, issueID))
+	}
+
+	return utils.AggregateErrors(errs)
+}
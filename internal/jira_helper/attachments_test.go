@@ -0,0 +1,82 @@
+package jira_helper_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/jira_helper"
+)
+
+var _ = Describe("Attachments", func() {
+	var fake *fakeJira
+	var server *httptest.Server
+	var info *jira_helper.JiraInfo
+	var report *ginkgoTypes.Report
+
+	BeforeEach(func() {
+		fake = newFakeJira()
+		server = httptest.NewServer(fake)
+		info = &jira_helper.JiraInfo{BaseURL: server.URL, Project: "E2E", Board: "e2e board", Username: "e2e",
+			IssueType: "Bug", Priority: "P1", AttachOutput: true, ArtifactEntries: []string{"cluster-dump", "screenshot"},
+			MaxAttachmentSize: 64}
+
+		dir := GinkgoT().TempDir()
+		dump := filepath.Join(dir, "dump.txt")
+		Expect(os.WriteFile(dump, []byte("cluster dump"), 0600)).To(Succeed())
+		screenshot := filepath.Join(dir, "screenshot.png")
+		Expect(os.WriteFile(screenshot, []byte(strings.Repeat("x", 65)), 0600)).To(Succeed())
+
+		specReport := getFailedSpecReport()
+		specReport.CapturedGinkgoWriterOutput = strings.Repeat("a", 100) + "end of output"
+		specReport.CapturedStdOutErr = "stderr"
+		specReport.ReportEntries = ginkgoTypes.ReportEntries{
+			{Name: "cluster-dump", Value: ginkgoTypes.WrapEntryValue(dump)},
+			{Name: "screenshot", Value: ginkgoTypes.WrapEntryValue(screenshot)},
+			{Name: "notes", Value: ginkgoTypes.WrapEntryValue(dump)},
+		}
+		report = &ginkgoTypes.Report{SpecReports: ginkgoTypes.SpecReports{specReport}}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("attaches outputs and artifact files to filed issues", func() {
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 12, info)).To(Succeed())
+
+		Expect(fake.attachments).To(HaveKey("101"))
+		attachments := fake.attachments["101"]
+		// screenshot is larger than MaxAttachmentSize and notes is not an artifact entry
+		Expect(attachments).To(HaveLen(3))
+		Expect(attachments).To(HaveKeyWithValue("run-12-stdout-stderr.txt", "stderr"))
+		Expect(attachments).To(HaveKeyWithValue("run-12-dump.txt", "cluster dump"))
+		Expect(attachments).To(HaveKey("run-12-ginkgo-writer-output.txt"))
+		Expect(attachments["run-12-ginkgo-writer-output.txt"]).To(HaveSuffix("end of output"))
+		Expect(attachments["run-12-ginkgo-writer-output.txt"]).To(HavePrefix("...[truncated]"))
+	})
+
+	It("attaches outputs and artifact files to existing issues", func() {
+		fake.issues = []jira.Issue{{ID: "1", Key: "E2E-1", Fields: &jira.IssueFields{Summary: "return ordered list"}}}
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 13, info)).To(Succeed())
+
+		Expect(fake.created).To(BeEmpty())
+		Expect(fake.attachments["1"]).To(HaveKey("run-13-dump.txt"))
+	})
+
+	It("does not attach anything unless configured", func() {
+		info.AttachOutput = false
+		info.ArtifactEntries = nil
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 12, info)).To(Succeed())
+
+		Expect(fake.created).To(HaveLen(1))
+		Expect(fake.attachments).To(BeEmpty())
+	})
+})
@@ -0,0 +1,161 @@
+package jira_helper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+)
+
+// adfNode is a node of an Atlassian Document Format (ADF) document, the format
+// jira Cloud REST API v3 expects for rich text fields like description and comments.
+type adfNode struct {
+	Type    string                 `json:"type"`
+	Version int                    `json:"version,omitempty"`
+	Text    string                 `json:"text,omitempty"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+	Content []adfNode              `json:"content,omitempty"`
+}
+
+// newADFDocument returns an ADF document containing blocks
+func newADFDocument(blocks ...adfNode) adfNode {
+	return adfNode{Type: "doc", Version: 1, Content: blocks}
+}
+
+// adfParagraph returns a paragraph. Lines of text are separated by hard breaks.
+func adfParagraph(text string) adfNode {
+	paragraph := adfNode{Type: "paragraph"}
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			paragraph.Content = append(paragraph.Content, adfNode{Type: "hardBreak"})
+		}
+		if line != "" {
+			paragraph.Content = append(paragraph.Content, adfNode{Type: "text", Text: line})
+		}
+	}
+	return paragraph
+}
+
+// adfCodeBlock returns a code block containing text
+func adfCodeBlock(text string) adfNode {
+	codeBlock := adfNode{Type: "codeBlock"}
+	// ADF does not allow empty text nodes
+	if text != "" {
+		codeBlock.Content = []adfNode{{Type: "text", Text: text}}
+	}
+	return codeBlock
+}
+
+// getReporter returns the user issues are filed by, as expected by JQL: the accountId
+// on jira Cloud (which does not support usernames in JQL), the username otherwise.
+// When not provided (i.e. personal access token is used), username is read from jira.
+func getReporter(ctx context.Context, jiraClient *jira.Client, info *JiraInfo) (string, error) {
+	if !info.Cloud && info.Username != "" {
+		return info.Username, nil
+	}
+
+	user, resp, err := jiraClient.User.GetSelfWithContext(ctx)
+	if err != nil {
+		utils.Byf(fmt.Sprintf("Failed to get current user. Error: %v. Resp %s", err, getResponseBody(resp)))
+		return "", fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	if info.Cloud {
+		if user.AccountID == "" {
+			return "", fmt.Errorf("failed to get accountId of current user")
+		}
+		return user.AccountID, nil
+	}
+	return user.Name, nil
+}
+
+// getAssignee returns the user an issue is assigned to, given the maintainer of the failed
+// test. On jira Cloud, maintainer (email or display name) is resolved to an accountId.
+// Returns nil if there is no maintainer or, on jira Cloud, no user matches it.
+func getAssignee(ctx context.Context, jiraClient *jira.Client, maintainer string, info *JiraInfo) *jira.User {
+	if maintainer == "" {
+		return nil
+	}
+	if !info.Cloud {
+		return &jira.User{Name: maintainer}
+	}
+
+	endpoint := fmt.Sprintf("rest/api/3/user/search?query=%s", url.QueryEscape(maintainer))
+	req, err := jiraClient.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		utils.Byf(fmt.Sprintf("Failed to search user %s. Error: %v", maintainer, err))
+		return nil
+	}
+
+	users := make([]jira.User, 0)
+	if resp, err := jiraClient.Do(req, &users); err != nil {
+		utils.Byf(fmt.Sprintf("Failed to search user %s. Error: %v. Resp %s", maintainer, err, getResponseBody(resp)))
+		return nil
+	}
+	if len(users) == 0 || users[0].AccountID == "" {
+		utils.Byf(fmt.Sprintf("No user found for maintainer %s. Issue will not be assigned", maintainer))
+		return nil
+	}
+
+	return &jira.User{AccountID: users[0].AccountID}
+}
+
+// createCloudIssue creates issue using jira Cloud REST API v3. description is
+// sent as an ADF document.
+func createCloudIssue(ctx context.Context, jiraClient *jira.Client, issue *jira.Issue,
+	description adfNode) (*jira.Issue, *jira.Response, error) {
+	// IssueFields marshaling takes care of custom fields
+	data, err := json.Marshal(issue.Fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, nil, err
+	}
+	fields["description"] = description
+
+	req, err := jiraClient.NewRequestWithContext(ctx, http.MethodPost, "rest/api/3/issue",
+		map[string]interface{}{"fields": fields})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	created := &jira.Issue{}
+	resp, err := jiraClient.Do(req, created)
+	if err != nil {
+		return nil, resp, jira.NewJiraError(resp, err)
+	}
+	return created, resp, nil
+}
+
+// postComment adds a comment to an issue. On jira Cloud the comment is sent, using REST
+// API v3, as ADF document. Otherwise text (wiki markup) is sent using REST API v2.
+func postComment(ctx context.Context, jiraClient *jira.Client, issueID, text string, document adfNode,
+	info *JiraInfo) error {
+	var resp *jira.Response
+	var err error
+	if info.Cloud {
+		var req *http.Request
+		endpoint := fmt.Sprintf("rest/api/3/issue/%s/comment", issueID)
+		req, err = jiraClient.NewRequestWithContext(ctx, http.MethodPost, endpoint,
+			map[string]interface{}{"body": document})
+		if err == nil {
+			resp, err = jiraClient.Do(req, nil)
+		}
+	} else {
+		_, resp, err = jiraClient.Issue.AddCommentWithContext(ctx, issueID, &jira.Comment{Body: text})
+	}
+
+	if err != nil {
+		utils.Byf(fmt.Sprintf("Failed to update issue %s. Error: %v. Resp %s", issueID, err, getResponseBody(resp)))
+		return fmt.Errorf("failed to add comment to issue %s: %w", issueID, err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package jira_helper_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http/httptest"
+
+	"github.com/andygrunwald/go-jira"
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/jira_helper"
+)
+
+var _ = Describe("Jira Cloud", func() {
+	var fake *fakeJira
+	var server *httptest.Server
+	var report *ginkgoTypes.Report
+
+	BeforeEach(func() {
+		fake = newFakeJira()
+		server = httptest.NewServer(fake)
+
+		specReport := getFailedSpecReport()
+		specReport.LeafNodeLabels = append(specReport.LeafNodeLabels, "maintainer:alice@org.com")
+		report = &ginkgoTypes.Report{SpecReports: ginkgoTypes.SpecReports{specReport}}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("VerifyInfo requires an API token on jira Cloud", func() {
+		info := &jira_helper.JiraInfo{BaseURL: server.URL, Project: "E2E", Board: "e2e board",
+			Username: "e2e@org.com", Password: "secret", Cloud: true}
+		Expect(jira_helper.VerifyInfo(context.TODO(), info)).To(MatchError(ContainSubstring("API token")))
+
+		info.APIToken = "token"
+		Expect(jira_helper.VerifyInfo(context.TODO(), info)).To(Succeed())
+	})
+
+	It("VerifyInfo requires either username or personal access token", func() {
+		info := &jira_helper.JiraInfo{BaseURL: server.URL, Project: "E2E", Board: "e2e board"}
+		Expect(jira_helper.VerifyInfo(context.TODO(), info)).To(HaveOccurred())
+
+		info.PersonalAccessToken = "pat"
+		Expect(jira_helper.VerifyInfo(context.TODO(), info)).To(Succeed())
+	})
+
+	It("uses API token for basic authentication", func() {
+		info := &jira_helper.JiraInfo{BaseURL: server.URL, Project: "E2E", Board: "e2e board",
+			Username: "e2e@org.com", APIToken: "token", IssueType: "Bug", Cloud: true}
+		_, err := jira_helper.GetOpenE2EJiraIssue(context.TODO(), info)
+		Expect(err).ToNot(HaveOccurred())
+
+		credentials := base64.StdEncoding.EncodeToString([]byte("e2e@org.com:token"))
+		Expect(fake.authHeaders).To(HaveEach("Basic " + credentials))
+	})
+
+	It("uses personal access token as Bearer token and reads username from jira", func() {
+		info := &jira_helper.JiraInfo{BaseURL: server.URL, Project: "E2E", Board: "e2e board",
+			PersonalAccessToken: "pat", IssueType: "Bug"}
+		_, err := jira_helper.GetOpenE2EJiraIssue(context.TODO(), info)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fake.authHeaders).To(HaveEach("Bearer pat"))
+		Expect(fake.searches).To(ConsistOf(jira_helper.GetOpenIssuesJQL(info, "e2e")))
+	})
+
+	It("searches by accountId and files issues in Atlassian Document Format on jira Cloud", func() {
+		fake.users["alice@org.com"] = "557058:alice"
+		info := &jira_helper.JiraInfo{BaseURL: server.URL, Project: "E2E", Board: "e2e board",
+			Username: "e2e@org.com", APIToken: "token", IssueType: "Bug", Priority: "P1", Cloud: true}
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 12, info)).To(Succeed())
+
+		Expect(fake.searches).To(ConsistOf(jira_helper.GetOpenIssuesJQL(info, "557058:e2e")))
+
+		Expect(fake.created).To(HaveLen(1))
+		Expect(fake.created[0]["assignee"]).To(HaveKeyWithValue("accountId", "557058:alice"))
+		Expect(fake.created[0]["description"]).To(Equal(map[string]interface{}{
+			"type": "doc", "version": float64(1),
+			"content": []interface{}{
+				map[string]interface{}{"type": "paragraph", "content": []interface{}{
+					map[string]interface{}{"type": "text", "text": ginkgo_helper.GetDescription(&report.SpecReports[0])},
+				}},
+			},
+		}))
+
+		Expect(fake.comments).To(BeEmpty())
+		Expect(fake.adfComments["101"]).To(HaveLen(1))
+		comment := fake.adfComments["101"][0].(map[string]interface{})
+		Expect(comment).To(HaveKeyWithValue("type", "doc"))
+		Expect(comment["content"]).To(ContainElement(map[string]interface{}{
+			"type": "codeBlock",
+			"content": []interface{}{
+				map[string]interface{}{"type": "text", "text": report.SpecReports[0].Failure.Location.FullStackTrace},
+			},
+		}))
+	})
+
+	It("does not assign issues when maintainer is not a jira Cloud user", func() {
+		info := &jira_helper.JiraInfo{BaseURL: server.URL, Project: "E2E", Board: "e2e board",
+			Username: "e2e@org.com", APIToken: "token", IssueType: "Bug", Priority: "P1", Cloud: true}
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 12, info)).To(Succeed())
+
+		Expect(fake.created).To(HaveLen(1))
+		Expect(fake.created[0]).ToNot(HaveKey("assignee"))
+	})
+
+	It("keeps wiki markup and usernames on jira Server", func() {
+		fake.issues = []jira.Issue{{ID: "1", Key: "E2E-1", Fields: &jira.IssueFields{Summary: "return ordered list"}}}
+		info := &jira_helper.JiraInfo{BaseURL: server.URL, Project: "E2E", Board: "e2e board",
+			Username: "e2e", Password: "secret", IssueType: "Bug", Priority: "P1"}
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 12, info)).To(Succeed())
+
+		Expect(fake.searches).To(ConsistOf(jira_helper.GetOpenIssuesJQL(info, "e2e")))
+		Expect(fake.adfComments).To(BeEmpty())
+		Expect(fake.comments["1"]).To(ConsistOf(HavePrefix("Run: 12\n\nFailure Location: /src/list_test.go:42")))
+	})
+})
@@ -0,0 +1,15 @@
+package jira_helper
+
+var (
+	GetPriority      = getPriority
+	GetCustomFields  = getCustomFields
+	GetOpenIssuesJQL = getOpenIssuesJQL
+
+	GetJiraProject    = getJiraProject
+	GetJiraBoard      = getJiraBoard
+	MoveIssueToSprint = moveIssueToSprint
+)
+
+func SetSearchPageSize(size int) {
+	searchPageSize = size
+}
@@ -0,0 +1,193 @@
+package jira_helper_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	. "github.com/onsi/gomega"
+)
+
+// fakeJira is a minimal jira server with project E2E and a board with id 5 whose
+// sprints are listed in sprints (by default an active sprint with id 7). When kanban
+// is set, board does not support sprints. Search returns all issues, at most maxPageSize (if set)
+// per page. Each issue offers the same transitions. Current user is e2e, with
+// accountId 557058:e2e. REST API v3 (jira Cloud) issue creation and comments are
+// supported as well.
+type fakeJira struct {
+	mu           sync.Mutex
+	issues       []jira.Issue
+	transitions  []jira.Transition
+	comments     map[string][]string // issue id -> comments
+	transitioned map[string]string   // issue id -> transition id
+	created      []map[string]interface{}
+	searches     []string                     // jql of each search
+	searchFields []string                     // fields requested by each search
+	maxPageSize  int                          // maximum number of issues returned per page. Unlimited if zero
+	sprintIssues map[string][]string          // sprint id -> issue ids moved to sprint
+	addedLabels  map[string][]string          // issue id -> labels added with an update
+	adfComments  map[string][]interface{}     // issue id -> comments added with REST API v3
+	users        map[string]string            // user search query -> accountId
+	authHeaders  []string                     // Authorization header of each request
+	attachments  map[string]map[string]string // issue id -> attachment name -> content
+	sprints      []jira.Sprint
+	kanban       bool
+}
+
+func newFakeJira(issues ...jira.Issue) *fakeJira {
+	now := time.Now()
+	start, end := now.Add(-time.Hour), now.Add(time.Hour)
+	return &fakeJira{
+		issues:       issues,
+		sprints:      []jira.Sprint{{ID: 7, Name: "Sprint 7", State: "active", StartDate: &start, EndDate: &end}},
+		comments:     make(map[string][]string),
+		transitioned: make(map[string]string),
+		sprintIssues: make(map[string][]string),
+		addedLabels:  make(map[string][]string),
+		adfComments:  make(map[string][]interface{}),
+		users:        make(map[string]string),
+		attachments:  make(map[string]map[string]string),
+	}
+}
+
+func (f *fakeJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.authHeaders = append(f.authHeaders, r.Header.Get("Authorization"))
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/rest/api/2/myself" && r.Method == http.MethodGet:
+		writeJSON(w, map[string]string{"name": "e2e", "accountId": "557058:e2e"})
+	case r.URL.Path == "/rest/api/3/user/search" && r.Method == http.MethodGet:
+		users := make([]map[string]string, 0)
+		if accountID, ok := f.users[r.URL.Query().Get("query")]; ok {
+			users = append(users, map[string]string{"accountId": accountID})
+		}
+		writeJSON(w, users)
+	case r.URL.Path == "/rest/api/2/project/E2E" && r.Method == http.MethodGet:
+		writeJSON(w, map[string]string{"id": "1", "key": "E2E"})
+	case r.URL.Path == "/rest/agile/1.0/board" && r.Method == http.MethodGet:
+		writeJSON(w, map[string]interface{}{"values": []map[string]interface{}{{"id": 5, "name": r.URL.Query().Get("name")}}})
+	case r.URL.Path == "/rest/agile/1.0/board/5/sprint" && r.Method == http.MethodGet:
+		f.listSprints(w, r)
+	case len(segments) == 6 && segments[3] == "sprint" && segments[5] == "issue" && r.Method == http.MethodPost:
+		body := map[string][]string{}
+		Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		f.sprintIssues[segments[4]] = append(f.sprintIssues[segments[4]], body["issues"]...)
+		w.WriteHeader(http.StatusNoContent)
+	case (r.URL.Path == "/rest/api/2/issue" || r.URL.Path == "/rest/api/3/issue") && r.Method == http.MethodPost:
+		body := map[string]map[string]interface{}{}
+		Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		f.created = append(f.created, body["fields"])
+		id := fmt.Sprintf("%d", 100+len(f.created))
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, map[string]string{"id": id, "key": "E2E-" + id})
+	case len(segments) == 5 && segments[3] == "issue" && r.Method == http.MethodPut:
+		body := map[string]map[string][]map[string]string{}
+		Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		for _, operation := range body["update"]["labels"] {
+			f.addedLabels[segments[4]] = append(f.addedLabels[segments[4]], operation["add"])
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/rest/api/2/search" && r.Method == http.MethodGet:
+		f.search(w, r)
+	case len(segments) == 6 && segments[3] == "issue" && segments[5] == "attachments" && r.Method == http.MethodPost:
+		f.attach(w, r, segments[4])
+	case len(segments) == 6 && segments[3] == "issue" && segments[5] == "transitions" && r.Method == http.MethodGet:
+		writeJSON(w, map[string]interface{}{"transitions": f.transitions})
+	case len(segments) == 6 && segments[3] == "issue" && segments[5] == "transitions" && r.Method == http.MethodPost:
+		body := map[string]map[string]string{}
+		Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		f.transitioned[segments[4]] = body["transition"]["id"]
+		w.WriteHeader(http.StatusNoContent)
+	case len(segments) == 6 && segments[2] == "3" && segments[3] == "issue" && segments[5] == "comment" &&
+		r.Method == http.MethodPost:
+		body := map[string]interface{}{}
+		Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		f.adfComments[segments[4]] = append(f.adfComments[segments[4]], body["body"])
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, map[string]string{"id": fmt.Sprintf("%d", len(f.adfComments[segments[4]]))})
+	case len(segments) == 6 && segments[3] == "issue" && segments[5] == "comment" && r.Method == http.MethodPost:
+		comment := jira.Comment{}
+		Expect(json.NewDecoder(r.Body).Decode(&comment)).To(Succeed())
+		f.comments[segments[4]] = append(f.comments[segments[4]], comment.Body)
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, map[string]string{"id": fmt.Sprintf("%d", len(f.comments[segments[4]]))})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errorMessages": ["not found"]}`)
+	}
+}
+
+// search returns the page of issues starting at startAt
+func (f *fakeJira) search(w http.ResponseWriter, r *http.Request) {
+	f.searches = append(f.searches, r.URL.Query().Get("jql"))
+	f.searchFields = append(f.searchFields, r.URL.Query().Get("fields"))
+
+	startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+	maxResults, _ := strconv.Atoi(r.URL.Query().Get("maxResults"))
+	if maxResults == 0 || (f.maxPageSize != 0 && maxResults > f.maxPageSize) {
+		maxResults = f.maxPageSize
+	}
+
+	issues := make([]jira.Issue, 0)
+	if startAt < len(f.issues) {
+		issues = f.issues[startAt:]
+	}
+	if maxResults != 0 && len(issues) > maxResults {
+		issues = issues[:maxResults]
+	}
+	writeJSON(w, map[string]interface{}{
+		"startAt": startAt, "maxResults": maxResults, "total": len(f.issues), "issues": issues,
+	})
+}
+
+// listSprints returns the sprints whose state is in the state parameter, if set
+func (f *fakeJira) listSprints(w http.ResponseWriter, r *http.Request) {
+	if f.kanban {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string][]string{"errorMessages": {"The board does not support sprints"}})
+		return
+	}
+
+	sprints := make([]jira.Sprint, 0)
+	states := r.URL.Query().Get("state")
+	for i := range f.sprints {
+		if states == "" || strings.Contains(states, f.sprints[i].State) {
+			sprints = append(sprints, f.sprints[i])
+		}
+	}
+	writeJSON(w, map[string]interface{}{"isLast": true, "values": sprints})
+}
+
+// attach records the file posted as attachment to issue
+func (f *fakeJira) attach(w http.ResponseWriter, r *http.Request, issueID string) {
+	Expect(r.Header.Get("X-Atlassian-Token")).ToNot(BeEmpty())
+	file, header, err := r.FormFile("file")
+	Expect(err).ToNot(HaveOccurred())
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	Expect(err).ToNot(HaveOccurred())
+
+	if f.attachments[issueID] == nil {
+		f.attachments[issueID] = make(map[string]string)
+	}
+	f.attachments[issueID][header.Filename] = string(content)
+	writeJSON(w, []map[string]interface{}{{"filename": header.Filename, "size": len(content)}})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	Expect(err).ToNot(HaveOccurred())
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,166 @@
+package jira_helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
+)
+
+// IssueTemplateData is the data custom field templates are executed with, for instance
+// "Found in run {{ .RunID }}"
+type IssueTemplateData struct {
+	// RunID is current run id
+	RunID string
+	// TestName is the name of the failed test
+	TestName string
+	// Summary is the issue summary
+	Summary string
+	// Maintainer is the test maintainer, if any
+	Maintainer string
+	// FailureLocation is the location (file:line) of the failure
+	FailureLocation string
+	// Labels contains all test labels
+	Labels []string
+}
+
+// getPriority returns the priority of the issue filed for a test:
+// - the priority mapped to the first test label found in info.PriorityByLabel;
+// - otherwise, info.SerialPriority for Serial tests (if set);
+// - otherwise, info.Priority.
+func getPriority(testReport *ginkgoTypes.SpecReport, info *JiraInfo) string {
+	labels := testReport.Labels()
+	for i := range labels {
+		if priority, ok := info.PriorityByLabel[labels[i]]; ok {
+			return priority
+		}
+	}
+
+	if info.SerialPriority != "" && ginkgo_helper.IsTestSerial(testReport) {
+		return info.SerialPriority
+	}
+
+	return info.Priority
+}
+
+// getAssigneeName returns the user the issue filed for a test is assigned to: test
+// maintainer if any, otherwise the jira assignee of test owner (see info.Ownership).
+func getAssigneeName(testReport *ginkgoTypes.SpecReport, maintainer string, info *JiraInfo) string {
+	if maintainer != "" {
+		return maintainer
+	}
+	if owner := info.Ownership.GetOwner(testReport); owner != nil {
+		return owner.JiraAssignee
+	}
+	return ""
+}
+
+// getComponent returns the component of the issue filed for a test: the jira component
+// of test owner (see info.Ownership) if any, otherwise info.Component.
+func getComponent(testReport *ginkgoTypes.SpecReport, info *JiraInfo) string {
+	if owner := info.Ownership.GetOwner(testReport); owner != nil && owner.JiraComponent != "" {
+		return owner.JiraComponent
+	}
+	return info.Component
+}
+
+// getCustomFields returns, per custom field id, the value to set on the issue filed for
+// a test. Values are templates executed with IssueTemplateData. A value which is a JSON
+// object or array (for instance [{"name": "1.2"}] for a version field) is sent as such.
+func getCustomFields(testReport *ginkgoTypes.SpecReport, runID, maintainer string,
+	info *JiraInfo) (map[string]interface{}, error) {
+	testName, _ := ginkgo_helper.GetTestNameAndMaintainer(testReport)
+	data := &IssueTemplateData{
+		RunID:           runID,
+		TestName:        testName,
+		Summary:         ginkgo_helper.GetSummary(testReport),
+		Maintainer:      maintainer,
+		FailureLocation: testReport.Failure.Location.String(),
+		Labels:          testReport.Labels(),
+	}
+
+	fields := make(map[string]interface{})
+	for _, id := range getSortedFieldIDs(info.CustomFields) {
+		tmpl, err := template.New(id).Parse(info.CustomFields[id])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse custom field %s: %w", id, err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to execute custom field %s: %w", id, err)
+		}
+
+		value := buf.String()
+		var decoded interface{}
+		if trimmed := strings.TrimSpace(value); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			if err := json.Unmarshal([]byte(trimmed), &decoded); err == nil {
+				fields[id] = decoded
+				continue
+			}
+		}
+		fields[id] = value
+	}
+
+	return fields, nil
+}
+
+// verifyCustomFields verifies all custom field templates can be parsed
+func verifyCustomFields(info *JiraInfo) error {
+	for _, id := range getSortedFieldIDs(info.CustomFields) {
+		if _, err := template.New(id).Parse(info.CustomFields[id]); err != nil {
+			return fmt.Errorf("failed to parse custom field %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// getOpenIssuesJQL returns the jql matching open issues filed by reporter in info.Project
+// (and info.Component, or any component owners are mapped to, if set)
+func getOpenIssuesJQL(info *JiraInfo, reporter string) string {
+	jql := fmt.Sprintf("project = %s and reporter = %s and type = %s",
+		quoteJQL(info.Project), quoteJQL(reporter), quoteJQL(info.IssueType))
+	if info.Component != "" {
+		components := []string{quoteJQL(info.Component)}
+		for _, component := range info.Ownership.Components() {
+			if component != info.Component {
+				components = append(components, quoteJQL(component))
+			}
+		}
+		if len(components) == 1 {
+			jql = fmt.Sprintf("%s and component = %s", jql, components[0])
+		} else {
+			jql = fmt.Sprintf("%s and component in (%s)", jql, strings.Join(components, ","))
+		}
+	}
+	if len(info.DoneStatuses) == 0 {
+		return jql
+	}
+
+	statuses := make([]string, len(info.DoneStatuses))
+	for i := range info.DoneStatuses {
+		statuses[i] = quoteJQL(info.DoneStatuses[i])
+	}
+	return fmt.Sprintf("%s and Status NOT IN (%s)", jql, strings.Join(statuses, ","))
+}
+
+// quoteJQL returns value as a JQL string literal
+func quoteJQL(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
+
+func getSortedFieldIDs(fields map[string]string) []string {
+	ids := make([]string, 0, len(fields))
+	for id := range fields {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,135 @@
+package jira_helper_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/jira_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ownership_helper"
+)
+
+func getFailedSpecReport() ginkgoTypes.SpecReport {
+	return ginkgoTypes.SpecReport{
+		LeafNodeType:   ginkgoTypes.NodeTypeIt,
+		LeafNodeText:   "return ordered list",
+		LeafNodeLabels: []string{"slow", "critical"},
+		State:          ginkgoTypes.SpecStateFailed,
+		NumAttempts:    1,
+		Failure: ginkgoTypes.Failure{
+			Message: "Expected true to be false",
+			Location: ginkgoTypes.CodeLocation{FileName: "/src/list_test.go", LineNumber: 42,
+				FullStackTrace: "github.com/org/e2e.glob..func1()\n\t/src/list_test.go:42 +0x1"},
+		},
+	}
+}
+
+var _ = Describe("Issue fields", func() {
+	var info *jira_helper.JiraInfo
+
+	BeforeEach(func() {
+		info = &jira_helper.JiraInfo{
+			Project:        "E2E",
+			Board:          "e2e board",
+			Username:       "e2e",
+			IssueType:      "Defect",
+			Priority:       "Medium",
+			SerialPriority: "High",
+			Labels:         []string{"e2e"},
+			DoneStatuses:   []string{"Done", "Won't Fix"},
+		}
+	})
+
+	It("getPriority maps test labels first, then Serial tests, then default", func() {
+		specReport := getFailedSpecReport()
+		Expect(jira_helper.GetPriority(&specReport, info)).To(Equal("Medium"))
+
+		specReport.IsSerial = true
+		Expect(jira_helper.GetPriority(&specReport, info)).To(Equal("High"))
+
+		info.PriorityByLabel = map[string]string{"critical": "Critical", "slow": "Low"}
+		Expect(jira_helper.GetPriority(&specReport, info)).To(Equal("Low"))
+	})
+
+	It("getCustomFields executes templates and decodes JSON values", func() {
+		info.CustomFields = map[string]string{
+			"customfield_1": "Found in run {{ .RunID }} ({{ .TestName }})",
+			"customfield_2": `[{"name": "{{ .RunID }}"}]`,
+			"customfield_3": "{{ .FailureLocation }}",
+		}
+		specReport := getFailedSpecReport()
+		fields, err := jira_helper.GetCustomFields(&specReport, "77", "user-a", info)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fields).To(HaveKeyWithValue("customfield_1", "Found in run 77 (return_ordered_list)"))
+		Expect(fields).To(HaveKeyWithValue("customfield_2", []interface{}{map[string]interface{}{"name": "77"}}))
+		Expect(fields).To(HaveKeyWithValue("customfield_3", "/src/list_test.go:42"))
+	})
+
+	It("VerifyInfo reports invalid custom field templates", func() {
+		info.CustomFields = map[string]string{"customfield_1": "{{ .RunID"}
+		Expect(jira_helper.VerifyInfo(context.TODO(), info)).To(MatchError(ContainSubstring("customfield_1")))
+	})
+
+	It("getOpenIssuesJQL uses issue type and done statuses", func() {
+		Expect(jira_helper.GetOpenIssuesJQL(info, "e2e")).To(Equal(
+			`project = "E2E" and reporter = "e2e" and type = "Defect" and Status NOT IN ("Done","Won't Fix")`))
+	})
+
+	It("files issues with configured type, priority, labels and custom fields", func() {
+		fake := newFakeJira()
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		info.BaseURL = server.URL
+		info.CustomFields = map[string]string{"customfield_10010": "run {{ .RunID }}"}
+		report := &ginkgoTypes.Report{SpecReports: ginkgoTypes.SpecReports{getFailedSpecReport()}}
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 77, info)).To(Succeed())
+
+		Expect(fake.searches).To(ContainElement(jira_helper.GetOpenIssuesJQL(info, info.Username)))
+		Expect(fake.created).To(HaveLen(1))
+		fields := fake.created[0]
+		Expect(fields["issuetype"]).To(HaveKeyWithValue("name", "Defect"))
+		Expect(fields["priority"]).To(HaveKeyWithValue("name", "Medium"))
+		Expect(fields["labels"]).To(ContainElement("e2e"))
+		Expect(fields["customfield_10010"]).To(Equal("run 77"))
+		Expect(fake.sprintIssues["7"]).To(ConsistOf("101"))
+	})
+
+	It("files issues with owner assignee and component and searches all owner components", func() {
+		fake := newFakeJira()
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		ownership, err := ownership_helper.Load(strings.NewReader(`
+owners:
+- labels: ["critical"]
+  jiraAssignee: alice
+  jiraComponent: Lists
+- labels: ["network"]
+  jiraComponent: Networking
+`))
+		Expect(err).ToNot(HaveOccurred())
+		info.BaseURL = server.URL
+		info.Component = "E2E"
+		info.Ownership = ownership
+		Expect(jira_helper.GetOpenIssuesJQL(info, "e2e")).To(Equal(`project = "E2E" and reporter = "e2e" and type = "Defect" ` +
+			`and component in ("E2E","Lists","Networking") and Status NOT IN ("Done","Won't Fix")`))
+
+		owned := getFailedSpecReport()
+		maintained := getFailedSpecReport()
+		maintained.LeafNodeText = "return sorted list"
+		maintained.LeafNodeLabels = append(maintained.LeafNodeLabels, "maintainer:bob")
+		report := &ginkgoTypes.Report{SpecReports: ginkgoTypes.SpecReports{owned, maintained}}
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 77, info)).To(Succeed())
+
+		Expect(fake.created).To(HaveLen(2))
+		Expect(fake.created[0]["assignee"]).To(HaveKeyWithValue("name", "alice"))
+		Expect(fake.created[0]["components"]).To(ConsistOf(HaveKeyWithValue("name", "Lists")))
+		// maintainer takes precedence over owner
+		Expect(fake.created[1]["assignee"]).To(HaveKeyWithValue("name", "bob"))
+	})
+})
@@ -0,0 +1,83 @@
+package jira_helper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+)
+
+const (
+	// TestLabelPrefix is the prefix of the label storing the id of the test an issue is filed for
+	TestLabelPrefix = "e2e-test-"
+
+	// FingerprintLabelPrefix is the prefix of the label storing the fingerprint of the failure
+	// an issue is filed for
+	FingerprintLabelPrefix = "e2e-fp-"
+)
+
+// getTestLabel returns the label identifying a test
+func getTestLabel(testReport *ginkgoTypes.SpecReport) string {
+	return TestLabelPrefix + ginkgo_helper.GetTestID(testReport)
+}
+
+// getFingerprintLabel returns the label identifying a test failure
+func getFingerprintLabel(testReport *ginkgoTypes.SpecReport) string {
+	return FingerprintLabelPrefix + ginkgo_helper.GetFingerprint(testReport)
+}
+
+// getIdentityLabels returns the labels identifying test and failure an issue is filed for
+func getIdentityLabels(testReport *ginkgoTypes.SpecReport) []string {
+	return []string{getTestLabel(testReport), getFingerprintLabel(testReport)}
+}
+
+// hasLabel returns true if issue has label
+func hasLabel(issue *jira.Issue, label string) bool {
+	if issue.Fields == nil {
+		return false
+	}
+	for i := range issue.Fields.Labels {
+		if issue.Fields.Labels[i] == label {
+			return true
+		}
+	}
+	return false
+}
+
+// isLegacyIssue returns true if issue was filed before fingerprinting was introduced,
+// i.e. it has no test id label
+func isLegacyIssue(issue *jira.Issue) bool {
+	if issue.Fields == nil {
+		return false
+	}
+	for i := range issue.Fields.Labels {
+		if strings.HasPrefix(issue.Fields.Labels[i], TestLabelPrefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// addLabelsToIssue adds labels to an existing issue
+func addLabelsToIssue(ctx context.Context, jiraClient *jira.Client, issueID string, labels []string) error {
+	operations := make([]map[string]string, len(labels))
+	for i := range labels {
+		operations[i] = map[string]string{"add": labels[i]}
+	}
+
+	data := map[string]interface{}{
+		"update": map[string]interface{}{"labels": operations},
+	}
+	if resp, err := jiraClient.Issue.UpdateIssueWithContext(ctx, issueID, data); err != nil {
+		utils.Byf(fmt.Sprintf("Failed to add labels to issue %s. Error: %v. Resp %s", issueID, err, getResponseBody(resp)))
+		return fmt.Errorf("failed to add labels to issue %s: %w", issueID, err)
+	}
+
+	utils.Byf(fmt.Sprintf("Added labels %v to issue %s", labels, issueID))
+	return nil
+}
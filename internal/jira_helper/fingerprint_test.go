@@ -0,0 +1,92 @@
+package jira_helper_test
+
+import (
+	"context"
+	"net/http/httptest"
+
+	"github.com/andygrunwald/go-jira"
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/jira_helper"
+)
+
+func getIdentityLabels(specReport *ginkgoTypes.SpecReport) []string {
+	return []string{
+		jira_helper.TestLabelPrefix + ginkgo_helper.GetTestID(specReport),
+		jira_helper.FingerprintLabelPrefix + ginkgo_helper.GetFingerprint(specReport),
+	}
+}
+
+var _ = Describe("Fingerprint", func() {
+	It("FindExistingIssue matches failed tests by fingerprint label", func() {
+		specReport := getFailedSpecReport()
+		shifted := getFailedSpecReport()
+		shifted.Failure.Location.LineNumber = 57
+		shifted.Failure.Location.FullStackTrace = "github.com/org/e2e.glob..func1()\n\t/src/list_test.go:57 +0x9"
+
+		openIssues := []jira.Issue{
+			{Key: "E2E-1", Fields: &jira.IssueFields{Summary: "return ordered list", Labels: []string{"e2e-test-other"}}},
+			{Key: "E2E-2", Fields: &jira.IssueFields{Summary: "return ordered list", Labels: getIdentityLabels(&specReport)}},
+		}
+		Expect(jira_helper.FindExistingIssue(openIssues, &shifted).Key).To(Equal("E2E-2"))
+	})
+
+	It("FindExistingIssue does not match fingerprinted issues of specs sharing leaf text", func() {
+		specReport := getFailedSpecReport()
+		other := getFailedSpecReport()
+		other.ContainerHierarchyTexts = []string{"Map"}
+
+		openIssues := []jira.Issue{
+			{Key: "E2E-1", Fields: &jira.IssueFields{Summary: "return ordered list", Labels: getIdentityLabels(&other)}},
+		}
+		Expect(jira_helper.FindExistingIssue(openIssues, &specReport)).To(BeNil())
+	})
+
+	It("FindExistingIssue matches issues filed before fingerprinting by description and summary", func() {
+		specReport := getFailedSpecReport()
+		openIssues := []jira.Issue{
+			{Key: "E2E-1", Fields: &jira.IssueFields{Summary: "return ordered list"}},
+			{Key: "E2E-2", Fields: &jira.IssueFields{Description: ginkgo_helper.GetDescription(&specReport)}},
+		}
+		Expect(jira_helper.FindExistingIssue(openIssues, &specReport).Key).To(Equal("E2E-2"))
+		Expect(jira_helper.FindExistingIssue(openIssues[:1], &specReport).Key).To(Equal("E2E-1"))
+	})
+
+	It("FindTestIssues returns all issues filed for a test", func() {
+		specReport := getFailedSpecReport()
+		other := getFailedSpecReport()
+		other.Failure.Location.FullStackTrace = "github.com/org/e2e.verifyOrder()\n\t/src/list_test.go:80 +0x9"
+
+		openIssues := []jira.Issue{
+			{Key: "E2E-1", Fields: &jira.IssueFields{Labels: getIdentityLabels(&specReport)}},
+			{Key: "E2E-2", Fields: &jira.IssueFields{Labels: getIdentityLabels(&other)}},
+			{Key: "E2E-3", Fields: &jira.IssueFields{Summary: "return ordered list"}},
+			{Key: "E2E-4", Fields: &jira.IssueFields{Summary: "something else"}},
+		}
+		specReport.State = ginkgoTypes.SpecStatePassed
+		keys := make([]string, 0)
+		for _, issue := range jira_helper.FindTestIssues(openIssues, &specReport) {
+			keys = append(keys, issue.Key)
+		}
+		Expect(keys).To(ConsistOf("E2E-1", "E2E-2", "E2E-3"))
+	})
+
+	It("adds identity labels to issues filed before fingerprinting", func() {
+		specReport := getFailedSpecReport()
+		fake := newFakeJira(jira.Issue{ID: "1", Key: "E2E-1", Fields: &jira.IssueFields{Summary: "return ordered list"}})
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		info := &jira_helper.JiraInfo{BaseURL: server.URL, Project: "E2E", Board: "e2e board", Username: "e2e",
+			IssueType: "Bug", Priority: "P1"}
+		report := &ginkgoTypes.Report{SpecReports: ginkgoTypes.SpecReports{specReport}}
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 12, info)).To(Succeed())
+
+		Expect(fake.created).To(BeEmpty())
+		Expect(fake.comments["1"]).To(HaveLen(1))
+		Expect(fake.addedLabels["1"]).To(Equal(getIdentityLabels(&specReport)))
+	})
+})
@@ -10,17 +10,58 @@ import (
 	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
 
 	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ownership_helper"
 	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
 )
 
+// searchPageSize is the number of issues requested with each search request.
+// Jira might return fewer issues per page.
+var searchPageSize = 100
+
+// searchFields are the issue fields needed to match open issues with tests
+var searchFields = []string{"summary", "description", "labels"}
+
 type JiraInfo struct {
 	BaseURL   string // jira base URL
 	Project   string // jira Project name
-	Board     string // jira Board Name
-	Component string // if not empty, any jira filed issue will have this as component
-	Username  string // jira username
+	Board     string // jira Board Name. Only needed to move issues to a sprint
+	Component string // if not empty, any jira filed issue will have this (or its owner) component
+	Username  string // jira username (the account email on jira Cloud)
 	Password  string // jira password
-	DryRun    bool   // indicates if this is a dryRun
+	// APIToken is the jira Cloud API token. Used, instead of Password, along with Username.
+	APIToken string
+	// PersonalAccessToken is the jira Server/Data Center personal access token, sent as Bearer
+	// token. Takes precedence over any other credential.
+	PersonalAccessToken string
+	// Cloud indicates jira is jira Cloud. Descriptions and comments are then sent in Atlassian
+	// Document Format using REST API v3 and users are identified by accountId.
+	Cloud  bool
+	DryRun bool // indicates if this is a dryRun
+
+	SprintPolicy SprintPolicy // which sprint, if any, issues are moved to. Empty is the same as SprintPolicyActive
+	SprintName   string       // name of the sprint issues are moved to with SprintPolicyNamed
+
+	AttachOutput      bool     // attach captured GinkgoWriter output and stdout/stderr of failed tests
+	ArtifactEntries   []string // names of report entries whose value is the path of a file to attach
+	MaxAttachmentSize int      // maximum size, in bytes, of attachments. Outputs are truncated, larger files skipped
+
+	// ResolveAfterPasses, if positive, is the number of consecutive runs a test must pass
+	// in for its open issue to be resolved. Zero disables auto-resolve.
+	ResolveAfterPasses int
+	// ResolvedStatus is the status issues are transitioned to when resolved
+	ResolvedStatus string
+
+	IssueType       string            // type of filed issues
+	Priority        string            // priority of filed issues
+	SerialPriority  string            // if not empty, priority of issues filed for Serial tests
+	PriorityByLabel map[string]string // priority, per test label, of filed issues. Takes precedence over other priorities
+	Labels          []string          // labels added to filed issues
+	CustomFields    map[string]string // value templates, per custom field id, set on filed issues
+	DoneStatuses    []string          // statuses of issues which are not open anymore
+
+	// Ownership, if not nil, maps tests to owners. Issues filed for tests with no maintainer
+	// are assigned to the owner jira assignee. Owner jira component takes precedence over Component.
+	Ownership *ownership_helper.Ownership
 }
 
 // VerifyInfo verifies provided jira info are correct
@@ -29,6 +70,18 @@ func VerifyInfo(ctx context.Context, info *JiraInfo) error {
 		return fmt.Errorf("VerifyInfo passed nil pointer")
 	}
 
+	if err := verifyCredentials(info); err != nil {
+		return err
+	}
+
+	if err := verifyCustomFields(info); err != nil {
+		return err
+	}
+
+	if err := verifySprintPolicy(info); err != nil {
+		return err
+	}
+
 	jiraClient, err := getJiraClient(info)
 	if err != nil {
 		return fmt.Errorf("failed to get jira client. Error %v", err)
@@ -37,6 +90,10 @@ func VerifyInfo(ctx context.Context, info *JiraInfo) error {
 		return fmt.Errorf("failed to get jira client")
 	}
 
+	if _, err := getReporter(ctx, jiraClient, info); err != nil {
+		return err
+	}
+
 	project, err := getJiraProject(ctx, jiraClient, info)
 	if err != nil {
 		return fmt.Errorf("failed to get jira project. Error %v", err)
@@ -45,29 +102,18 @@ func VerifyInfo(ctx context.Context, info *JiraInfo) error {
 		return fmt.Errorf("failed to get jira project")
 	}
 
-	board, err := getJiraBoard(ctx, jiraClient, project.Key, info)
-	if err != nil {
-		return fmt.Errorf("failed to get jira board. Error %v", err)
-	}
-	if board == nil {
-		return fmt.Errorf("failed to get jira board")
-	}
-
-	sprint, err := getJiraActiveSprint(ctx, jiraClient, fmt.Sprintf("%d", board.ID))
-	if err != nil {
+	if _, err := getTargetSprint(ctx, jiraClient, project.Key, info); err != nil {
 		return err
 	}
-	if sprint == nil {
-		return fmt.Errorf("failed to get jira active sprint")
-	}
 
 	return nil
 }
 
 // FileJiraIssuesForFailedTests files an issue, if needed, for a failed test.
-// If filed, an issue is added to active sprint.
+// Issues are moved to a sprint according to info.SprintPolicy.
 // Before filing a new issue, this method search if one already exists. If so,
 // it simply adds a comment with run id.
+// Test artifacts are attached to both new and existing issues.
 // - report is the list of tests
 // - runID is current run id
 func FileJiraIssuesForFailedTests(ctx context.Context, report *ginkgoTypes.Report, runID int64,
@@ -86,28 +132,19 @@ func FileJiraIssuesForFailedTests(ctx context.Context, report *ginkgoTypes.Repor
 		return fmt.Errorf("%s", msg)
 	}
 
-	board, err := getJiraBoard(ctx, jiraClient, project.Key, info)
-	if err != nil || board == nil {
-		msg := "Failed to get jira board"
-		utils.Byf(msg)
-		return fmt.Errorf("%s", msg)
-	}
-
-	activeSprint, err := getJiraActiveSprint(ctx, jiraClient, fmt.Sprintf("%d", board.ID))
-	if err != nil || activeSprint == nil {
-		msg := "Failed to get active sprint"
-		utils.Byf(msg)
-		return fmt.Errorf("%s", msg)
+	sprint, err := getTargetSprint(ctx, jiraClient, project.Key, info)
+	if err != nil {
+		utils.Byf("Failed to get sprint")
+		return err
 	}
 
 	openIssues, err := GetOpenE2EJiraIssue(ctx, info)
 	if err != nil {
-		msg := "Failed to get open jira issue"
-		utils.Byf(msg)
-		return fmt.Errorf("%s", msg)
+		utils.Byf("Failed to get open jira issue")
+		return err
 	}
 
-	priority := jira.Priority{Name: "P1"}
+	errs := make([]error, 0)
 	for i := range report.SpecReports {
 		testReport := report.SpecReports[i]
 		testName, maintainer := ginkgo_helper.GetTestNameAndMaintainer(&testReport)
@@ -120,31 +157,72 @@ func FileJiraIssuesForFailedTests(ctx context.Context, report *ginkgoTypes.Repor
 				if info.DryRun {
 					continue
 				}
-				addCommentToIssue(ctx, jiraClient, openIssue.ID, fmt.Sprintf("%d", runID), &testReport)
-				moveIssueToSprint(ctx, jiraClient, activeSprint.ID, openIssue.ID)
+				if isLegacyIssue(openIssue) {
+					if err := addLabelsToIssue(ctx, jiraClient, openIssue.ID, getIdentityLabels(&testReport)); err != nil {
+						errs = append(errs, fmt.Errorf("test %s: %w", testName, err))
+					}
+				}
+				if err := addCommentToIssue(ctx, jiraClient, openIssue.ID, fmt.Sprintf("%d", runID), &testReport, info); err != nil {
+					errs = append(errs, fmt.Errorf("test %s: %w", testName, err))
+					continue
+				}
+				if err := attachArtifacts(ctx, jiraClient, openIssue.ID, fmt.Sprintf("%d", runID), &testReport, info); err != nil {
+					errs = append(errs, fmt.Errorf("test %s: %w", testName, err))
+				}
+				if shouldMoveToSprint(sprint, false, info) {
+					if err := moveIssueToSprint(ctx, jiraClient, sprint.ID, openIssue.ID); err != nil {
+						errs = append(errs, fmt.Errorf("test %s: %w", testName, err))
+					}
+				}
 			} else {
 				utils.Byf(fmt.Sprintf("Filing issue for test %s", testName))
 				if info.DryRun {
 					continue
 				}
-				_ = createIssue(ctx, jiraClient, activeSprint, &priority, project.Key,
-					info.Component, maintainer, fmt.Sprintf("%d", runID), &testReport)
+				if _, err := createIssue(ctx, jiraClient, sprint, project.Key,
+					getAssigneeName(&testReport, maintainer, info), fmt.Sprintf("%d", runID), &testReport, info); err != nil {
+					errs = append(errs, fmt.Errorf("test %s: %w", testName, err))
+				}
 			}
 		}
 	}
 
+	return utils.AggregateErrors(errs)
+}
+
+// verifyCredentials verifies the user issues are filed by can be identified
+func verifyCredentials(info *JiraInfo) error {
+	if info.PersonalAccessToken != "" {
+		return nil
+	}
+	if info.Username == "" {
+		return fmt.Errorf("either username or personal access token is required")
+	}
+	if info.Cloud && info.APIToken == "" {
+		return fmt.Errorf("jira Cloud requires an API token")
+	}
 	return nil
 }
 
-// getJiraClient returns a new Jira API client.
+// getJiraClient returns a new Jira API client. Personal access token, if set, is used
+// as Bearer token. Otherwise username along with API token (or password) are used for
+// basic authentication.
 func getJiraClient(info *JiraInfo) (*jira.Client, error) {
 	var jiraClient *jira.Client
 	var err error
-	if info.Username != "" && info.Password != "" {
+	if info.PersonalAccessToken != "" {
+		tp := jira.PATAuthTransport{
+			Token: info.PersonalAccessToken,
+		}
+		jiraClient, err = jira.NewClient(tp.Client(), info.BaseURL)
+	} else if info.Username != "" && (info.APIToken != "" || info.Password != "") {
 		tp := jira.BasicAuthTransport{
 			Username: info.Username,
 			Password: info.Password,
 		}
+		if info.APIToken != "" {
+			tp.Password = info.APIToken
+		}
 		jiraClient, err = jira.NewClient(tp.Client(), info.BaseURL)
 	} else {
 		jiraClient, err = jira.NewClient(nil, info.BaseURL)
@@ -164,8 +242,8 @@ func getJiraProject(ctx context.Context, jiraClient *jira.Client, info *JiraInfo
 	req, _ := jiraClient.NewRequestWithContext(ctx, "GET", url, nil)
 	project := &jira.Project{}
 	if resp, err := jiraClient.Do(req, project); err != nil {
-		body, _ := io.ReadAll(resp.Body)
-		utils.Byf(fmt.Sprintf("Failed to get project with name: %s. Error: %v. Response: %s", info.Project, err, string(body)))
+		utils.Byf(fmt.Sprintf("Failed to get project with name: %s. Error: %v. Response: %s", info.Project, err,
+			getResponseBody(resp)))
 		return nil, err
 	}
 
@@ -179,8 +257,7 @@ func getJiraBoard(ctx context.Context, jiraClient *jira.Client, projectKey strin
 	boardListOptions := &jira.BoardListOptions{ProjectKeyOrID: projectKey, Name: info.Board}
 	boardList, resp, err := jiraClient.Board.GetAllBoardsWithContext(ctx, boardListOptions)
 	if err != nil {
-		body, _ := io.ReadAll(resp.Body)
-		utils.Byf(fmt.Sprintf("Failed to get board list. Error %v. Response: %s", err, string(body)))
+		utils.Byf(fmt.Sprintf("Failed to get board list. Error %v. Response: %s", err, getResponseBody(resp)))
 		return nil, err
 	}
 
@@ -234,116 +311,201 @@ func getJiraActiveSprint(ctx context.Context, jiraClient *jira.Client, boardID s
 	return activeSprint, nil
 }
 
-// getJiraIssues finds all issues matching passed jql
+// getJiraIssues finds all issues matching passed jql. Results are fetched one page
+// at a time and only searchFields are requested.
 func getJiraIssues(ctx context.Context, jiraClient *jira.Client, jql string) ([]jira.Issue, error) {
-	issues, _, err := jiraClient.Issue.SearchWithContext(ctx, jql, nil)
-	if err != nil {
-		utils.Byf(fmt.Sprintf("Failed to get all issues matching jql:%s. Error: %v", jql, err))
-		return nil, err
+	options := &jira.SearchOptions{MaxResults: searchPageSize, Fields: searchFields}
+	issues := make([]jira.Issue, 0)
+	for {
+		page, resp, err := jiraClient.Issue.SearchWithContext(ctx, jql, options)
+		if err != nil {
+			utils.Byf(fmt.Sprintf("Failed to get all issues matching jql:%s. Error: %v. Resp %s",
+				jql, err, getResponseBody(resp)))
+			return nil, err
+		}
+
+		issues = append(issues, page...)
+		// Jira can return fewer issues than requested, so next page starts after
+		// the last issue received
+		options.StartAt += len(page)
+		if len(page) == 0 || options.StartAt >= resp.Total {
+			break
+		}
 	}
 
 	return issues, nil
 }
 
-// FindExistingIssue finds if an already existing issue exists.
+// FindExistingIssue finds if an already existing issue exists:
+// - for a failed test, the issue labeled with the failure fingerprint;
+// - for any other test, the issue labeled with the test id.
+// Issues filed before fingerprinting was introduced (which have no test id label) are
+// matched by description first and by summary then.
 func FindExistingIssue(openIssues []jira.Issue, testReport *ginkgoTypes.SpecReport) *jira.Issue {
-	// First search for a match with description.
-	// Description contains both test name and failure location.
+	label := getTestLabel(testReport)
+	if testReport.Failed() {
+		label = getFingerprintLabel(testReport)
+	}
+	for i := range openIssues {
+		if hasLabel(&openIssues[i], label) {
+			return &openIssues[i]
+		}
+	}
+
+	return findLegacyIssue(openIssues, testReport)
+}
+
+// FindTestIssues returns all open issues filed for a test, whatever the failure was.
+func FindTestIssues(openIssues []jira.Issue, testReport *ginkgoTypes.SpecReport) []*jira.Issue {
+	issues := make([]*jira.Issue, 0)
+	label := getTestLabel(testReport)
+	for i := range openIssues {
+		if hasLabel(&openIssues[i], label) {
+			issues = append(issues, &openIssues[i])
+		}
+	}
+
+	if legacyIssue := findLegacyIssue(openIssues, testReport); legacyIssue != nil {
+		issues = append(issues, legacyIssue)
+	}
+	return issues
+}
+
+// findLegacyIssue finds, among issues with no test id label, the one matching test
+// description (which contains both test name and failure location) or, if none,
+// test summary.
+func findLegacyIssue(openIssues []jira.Issue, testReport *ginkgoTypes.SpecReport) *jira.Issue {
 	description := ginkgo_helper.GetDescription(testReport)
 	for i := range openIssues {
-		if openIssues[i].Fields != nil && openIssues[i].Fields.Description == description {
+		if isLegacyIssue(&openIssues[i]) && openIssues[i].Fields.Description == description {
 			return &openIssues[i]
 		}
 	}
 
-	// Search for an issue matching summary only
 	summary := ginkgo_helper.GetSummary(testReport)
 	for i := range openIssues {
-		if openIssues[i].Fields != nil && openIssues[i].Fields.Summary == summary {
+		if isLegacyIssue(&openIssues[i]) && openIssues[i].Fields.Summary == summary {
 			return &openIssues[i]
 		}
 	}
 	return nil
 }
 
-// createIssue creates new issue of type bug which will be added to sprint
+// createIssue creates new issue of type info.IssueType which will be added to sprint (if not nil)
 // - Comments will contain run ID, failure message and full stack trace
+// - Test artifacts (see getAttachments) are attached
 // - Assignee is the user the bug will be assigned to
 // - Reporter is the issue reporter
-// Return the issue Key or empty an error occurred.
-func createIssue(ctx context.Context, jiraClient *jira.Client, sprint *jira.Sprint, priority *jira.Priority,
-	projectKey, componentName, assignee, runID string,
-	testReport *ginkgoTypes.SpecReport) string {
+// Return the issue Key or an error if any occurred.
+func createIssue(ctx context.Context, jiraClient *jira.Client, sprint *jira.Sprint,
+	projectKey, assignee, runID string, testReport *ginkgoTypes.SpecReport, info *JiraInfo) (string, error) {
 	summary := ginkgo_helper.GetSummary(testReport)
 
+	customFields, err := getCustomFields(testReport, runID, assignee, info)
+	if err != nil {
+		return "", err
+	}
+
+	description := ginkgo_helper.GetDescription(testReport)
 	i := jira.Issue{
 		Fields: &jira.IssueFields{
-			Description: ginkgo_helper.GetDescription(testReport),
 			Type: jira.IssueType{
-				Name: "Bug",
+				Name: info.IssueType,
 			},
 			Project: jira.Project{
 				Key: projectKey,
 			},
 			Summary:  summary,
-			Priority: priority,
+			Priority: &jira.Priority{Name: getPriority(testReport, info)},
+			Labels:   append(append([]string{}, info.Labels...), getIdentityLabels(testReport)...),
 		},
 	}
 
-	if componentName != "" {
-		component := jira.Component{Name: componentName}
-		i.Fields.Components = []*jira.Component{&component}
+	if len(customFields) != 0 {
+		i.Fields.Unknowns = customFields
 	}
 
-	if assignee != "" {
-		i.Fields.Assignee = &jira.User{Name: assignee}
+	if name := getComponent(testReport, info); name != "" {
+		component := jira.Component{Name: name}
+		i.Fields.Components = []*jira.Component{&component}
 	}
 
-	issue, resp, err := jiraClient.Issue.CreateWithContext(ctx, &i)
+	i.Fields.Assignee = getAssignee(ctx, jiraClient, assignee, info)
+
+	var issue *jira.Issue
+	var resp *jira.Response
+	if info.Cloud {
+		issue, resp, err = createCloudIssue(ctx, jiraClient, &i, newADFDocument(adfParagraph(description)))
+	} else {
+		i.Fields.Description = description
+		issue, resp, err = jiraClient.Issue.CreateWithContext(ctx, &i)
+	}
 	if err != nil {
-		body, _ := io.ReadAll(resp.Body)
-		utils.Byf(fmt.Sprintf("Failed to create issue. Error: %v. Resp %s", err, string(body)))
-		return ""
+		utils.Byf(fmt.Sprintf("Failed to create issue. Error: %v. Resp %s", err, getResponseBody(resp)))
+		return "", fmt.Errorf("failed to create issue: %w", err)
 	}
 
 	utils.Byf(fmt.Sprintf("Created issue %s", issue.Key))
 
-	addCommentToIssue(ctx, jiraClient, issue.ID, runID, testReport)
+	if err := addCommentToIssue(ctx, jiraClient, issue.ID, runID, testReport, info); err != nil {
+		return issue.Key, err
+	}
+
+	// A missing attachment does not prevent issue from being moved to sprint
+	attachErr := attachArtifacts(ctx, jiraClient, issue.ID, runID, testReport, info)
 
-	moveIssueToSprint(ctx, jiraClient, sprint.ID, issue.ID)
+	if shouldMoveToSprint(sprint, true, info) {
+		if err := moveIssueToSprint(ctx, jiraClient, sprint.ID, issue.ID); err != nil {
+			return issue.Key, err
+		}
+	}
 
-	return issue.Key
+	return issue.Key, attachErr
 }
 
 // addCommentToIssue append comment to current open issue while also resetting sprint and priority.
 // The new appended comment will contain buildEnvironment (VCS vs UCS), run ID, failure message and full stack trace
 func addCommentToIssue(ctx context.Context, jiraClient *jira.Client, issueID string,
-	runID string, testReport *ginkgoTypes.SpecReport) {
-	comment := jira.Comment{
-		Body: fmt.Sprintf("Run: %s\n\nFailure Location: %s\n\nFull Stack Trace %s",
-			runID, testReport.Failure.Location.String(),
-			testReport.Failure.Location.FullStackTrace),
-	}
-
-	if _, resp, err := jiraClient.Issue.AddCommentWithContext(ctx, issueID, &comment); err != nil {
-		body, _ := io.ReadAll(resp.Body)
-		utils.Byf(fmt.Sprintf("Failed to update issue %s. Error: %v. Resp %s", issueID, err, string(body)))
-		return
+	runID string, testReport *ginkgoTypes.SpecReport, info *JiraInfo) error {
+	text := fmt.Sprintf("Run: %s\n\nFailure Location: %s\n\nFull Stack Trace %s",
+		runID, testReport.Failure.Location.String(),
+		testReport.Failure.Location.FullStackTrace)
+	document := newADFDocument(
+		adfParagraph(fmt.Sprintf("Run: %s", runID)),
+		adfParagraph(fmt.Sprintf("Failure Location: %s", testReport.Failure.Location.String())),
+		adfParagraph("Full Stack Trace"),
+		adfCodeBlock(testReport.Failure.Location.FullStackTrace),
+	)
+
+	if err := postComment(ctx, jiraClient, issueID, text, document, info); err != nil {
+		return err
 	}
 
 	utils.Byf("Update issue with comment")
+	return nil
+}
+
+// getResponseBody returns the body of a jira response. Response is nil when
+// request could not be sent.
+func getResponseBody(resp *jira.Response) string {
+	if resp == nil || resp.Body == nil {
+		return ""
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
 }
 
-func moveIssueToSprint(ctx context.Context, jiraClient *jira.Client, sprintID int, issueID string) {
+func moveIssueToSprint(ctx context.Context, jiraClient *jira.Client, sprintID int, issueID string) error {
 	if resp, err := jiraClient.Sprint.MoveIssuesToSprintWithContext(ctx, sprintID, []string{issueID}); err != nil {
-		body, _ := io.ReadAll(resp.Body)
-		utils.Byf(fmt.Sprintf("Failed to update issue %s. Error: %v. Resp %s", issueID, err, string(body)))
-		return
+		utils.Byf(fmt.Sprintf("Failed to update issue %s. Error: %v. Resp %s", issueID, err, getResponseBody(resp)))
+		return fmt.Errorf("failed to move issue %s to sprint %d: %w", issueID, sprintID, err)
 	}
 	utils.Byf("Moved issue to sprint")
+	return nil
 }
 
-// GetOpenE2EJiraIssue returns issues filed by user (in jiraInfo)
+// GetOpenE2EJiraIssue returns issues filed by user (in jiraInfo). On jira Cloud, user
+// accountId is used.
 func GetOpenE2EJiraIssue(ctx context.Context, info *JiraInfo) ([]jira.Issue, error) {
 	jiraClient, err := getJiraClient(info)
 	if err != nil || jiraClient == nil {
@@ -351,11 +513,16 @@ func GetOpenE2EJiraIssue(ctx context.Context, info *JiraInfo) ([]jira.Issue, err
 		return nil, fmt.Errorf("failed to get jira client")
 	}
 
-	jql := fmt.Sprintf("reporter = %s and type = Bug and Status NOT IN (Resolved,Closed)", info.Username)
+	reporter, err := getReporter(ctx, jiraClient, info)
+	if err != nil {
+		return nil, err
+	}
+
+	jql := getOpenIssuesJQL(info, reporter)
 	openIssues, err := getJiraIssues(ctx, jiraClient, jql)
 	if err != nil {
 		utils.Byf("Failed to get open jira issue")
-		return nil, fmt.Errorf("failed to get open jira issue")
+		return nil, fmt.Errorf("failed to get open jira issue: %w", err)
 	}
 
 	return openIssues, nil
@@ -0,0 +1,13 @@
+package jira_helper_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestJiraHelper(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "JiraHelper Suite")
+}
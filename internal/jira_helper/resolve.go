@@ -0,0 +1,123 @@
+package jira_helper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+)
+
+// IsPassed returns true if test passed at first attempt. Flaky tests are not
+// considered passed, so their issues are not resolved.
+func IsPassed(testReport *ginkgoTypes.SpecReport) bool {
+	return testReport.State == ginkgoTypes.SpecStatePassed && !ginkgo_helper.IsFlaky(testReport)
+}
+
+// ResolveIssuesForPassedTests resolves the open issues, if any, of tests which passed in
+// enough consecutive runs.
+// - report is the list of tests
+// - passingSince contains, per test name, the first of the consecutive runs the test passed in.
+// Only tests which passed in at least info.ResolveAfterPasses consecutive runs must be included.
+// For each issue, a comment is added and the issue is transitioned to info.ResolvedStatus.
+func ResolveIssuesForPassedTests(ctx context.Context, report *ginkgoTypes.Report, passingSince map[string]int64,
+	info *JiraInfo) error {
+	if len(passingSince) == 0 {
+		return nil
+	}
+
+	jiraClient, err := getJiraClient(info)
+	if err != nil || jiraClient == nil {
+		msg := "Failed to get jira client"
+		utils.Byf(msg)
+		return fmt.Errorf("%s", msg)
+	}
+
+	openIssues, err := GetOpenE2EJiraIssue(ctx, info)
+	if err != nil {
+		utils.Byf("Failed to get open jira issue")
+		return err
+	}
+
+	resolved := make(map[string]bool)
+	errs := make([]error, 0)
+	for i := range report.SpecReports {
+		testReport := &report.SpecReports[i]
+		if !IsPassed(testReport) {
+			continue
+		}
+
+		testName, _ := ginkgo_helper.GetTestNameAndMaintainer(testReport)
+		since, ok := passingSince[testName]
+		if !ok {
+			continue
+		}
+
+		for _, openIssue := range FindTestIssues(openIssues, testReport) {
+			if resolved[openIssue.Key] {
+				continue
+			}
+			resolved[openIssue.Key] = true
+
+			utils.Byf(fmt.Sprintf("Resolving issue %s for test %s passing since run %d", openIssue.Key, testName, since))
+			if info.DryRun {
+				continue
+			}
+			if err := resolveIssue(ctx, jiraClient, openIssue.ID, since, info); err != nil {
+				errs = append(errs, fmt.Errorf("test %s: %w", testName, err))
+			}
+		}
+	}
+
+	return utils.AggregateErrors(errs)
+}
+
+// resolveIssue adds a comment with the run test is passing since and transitions issue
+// to info.ResolvedStatus.
+func resolveIssue(ctx context.Context, jiraClient *jira.Client, issueID string, passingSince int64,
+	info *JiraInfo) error {
+	status := info.ResolvedStatus
+	transitions, resp, err := jiraClient.Issue.GetTransitionsWithContext(ctx, issueID)
+	if err != nil {
+		utils.Byf(fmt.Sprintf("Failed to get transitions for issue %s. Error: %v. Resp %s", issueID, err, getResponseBody(resp)))
+		return fmt.Errorf("failed to get transitions for issue %s: %w", issueID, err)
+	}
+
+	transition := findTransition(transitions, status)
+	if transition == nil {
+		return fmt.Errorf("no transition to status %s available for issue %s", status, issueID)
+	}
+
+	text := fmt.Sprintf("Passing since run %d", passingSince)
+	if err := postComment(ctx, jiraClient, issueID, text, newADFDocument(adfParagraph(text)), info); err != nil {
+		return err
+	}
+
+	if resp, err := jiraClient.Issue.DoTransitionWithContext(ctx, issueID, transition.ID); err != nil {
+		utils.Byf(fmt.Sprintf("Failed to transition issue %s. Error: %v. Resp %s", issueID, err, getResponseBody(resp)))
+		return fmt.Errorf("failed to transition issue %s to status %s: %w", issueID, status, err)
+	}
+
+	utils.Byf(fmt.Sprintf("Moved issue %s to status %s", issueID, status))
+	return nil
+}
+
+// findTransition returns the transition leading to status. Transitions are matched,
+// case insensitive, by target status name first and by transition name then.
+func findTransition(transitions []jira.Transition, status string) *jira.Transition {
+	for i := range transitions {
+		if strings.EqualFold(transitions[i].To.Name, status) {
+			return &transitions[i]
+		}
+	}
+	for i := range transitions {
+		if strings.EqualFold(transitions[i].Name, status) {
+			return &transitions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package jira_helper_test
+
+import (
+	"context"
+	"net/http/httptest"
+
+	"github.com/andygrunwald/go-jira"
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/jira_helper"
+)
+
+var _ = Describe("ResolveIssuesForPassedTests", func() {
+	var fake *fakeJira
+	var server *httptest.Server
+	var info *jira_helper.JiraInfo
+	var report *ginkgoTypes.Report
+
+	BeforeEach(func() {
+		fake = newFakeJira(
+			jira.Issue{ID: "1", Key: "E2E-1", Fields: &jira.IssueFields{Summary: "return ordered list"}},
+			jira.Issue{ID: "2", Key: "E2E-2", Fields: &jira.IssueFields{Summary: "return sorted list"}},
+		)
+		fake.transitions = []jira.Transition{
+			{ID: "11", Name: "Start Progress", To: jira.Status{Name: "In Progress"}},
+			{ID: "21", Name: "Resolve Issue", To: jira.Status{Name: "Resolved"}},
+		}
+		server = httptest.NewServer(fake)
+		info = &jira_helper.JiraInfo{BaseURL: server.URL, Username: "e2e", ResolvedStatus: "resolved"}
+
+		report = &ginkgoTypes.Report{
+			SpecReports: ginkgoTypes.SpecReports{
+				{LeafNodeType: ginkgoTypes.NodeTypeIt, LeafNodeText: "return ordered list",
+					State: ginkgoTypes.SpecStatePassed, NumAttempts: 1},
+				{LeafNodeType: ginkgoTypes.NodeTypeIt, LeafNodeText: "return sorted list",
+					State: ginkgoTypes.SpecStatePassed, NumAttempts: 1},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("comments and transitions issues of tests passing since a run", func() {
+		passingSince := map[string]int64{"return_ordered_list": 7}
+		Expect(jira_helper.ResolveIssuesForPassedTests(context.TODO(), report, passingSince, info)).To(Succeed())
+
+		Expect(fake.comments).To(HaveKeyWithValue("1", []string{"Passing since run 7"}))
+		Expect(fake.transitioned).To(HaveKeyWithValue("1", "21"))
+		Expect(fake.transitioned).ToNot(HaveKey("2"))
+	})
+
+	It("does not resolve issues of flaky tests", func() {
+		report.SpecReports[0].NumAttempts = 2
+		passingSince := map[string]int64{"return_ordered_list": 7}
+		Expect(jira_helper.ResolveIssuesForPassedTests(context.TODO(), report, passingSince, info)).To(Succeed())
+		Expect(fake.transitioned).To(BeEmpty())
+	})
+
+	It("reports an error when no transition leads to resolved status", func() {
+		info.ResolvedStatus = "Done"
+		passingSince := map[string]int64{"return_ordered_list": 7}
+		Expect(jira_helper.ResolveIssuesForPassedTests(context.TODO(), report, passingSince, info)).ToNot(Succeed())
+		Expect(fake.comments).To(BeEmpty())
+	})
+
+	It("does not modify issues on dry run", func() {
+		info.DryRun = true
+		passingSince := map[string]int64{"return_ordered_list": 7}
+		Expect(jira_helper.ResolveIssuesForPassedTests(context.TODO(), report, passingSince, info)).To(Succeed())
+		Expect(fake.comments).To(BeEmpty())
+		Expect(fake.transitioned).To(BeEmpty())
+	})
+
+	It("matches transitions by name when no target status matches", func() {
+		info.ResolvedStatus = "Start progress"
+		passingSince := map[string]int64{"return_sorted_list": 3}
+		Expect(jira_helper.ResolveIssuesForPassedTests(context.TODO(), report, passingSince, info)).To(Succeed())
+		Expect(fake.transitioned).To(HaveKeyWithValue("2", "11"))
+	})
+})
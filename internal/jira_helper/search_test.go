@@ -0,0 +1,66 @@
+package jira_helper_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+
+	"github.com/andygrunwald/go-jira"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/jira_helper"
+)
+
+var _ = Describe("GetOpenE2EJiraIssue", func() {
+	var fake *fakeJira
+	var server *httptest.Server
+	var info *jira_helper.JiraInfo
+
+	BeforeEach(func() {
+		issues := make([]jira.Issue, 0)
+		for i := 1; i <= 7; i++ {
+			issues = append(issues, jira.Issue{ID: fmt.Sprintf("%d", i), Key: fmt.Sprintf("E2E-%d", i),
+				Fields: &jira.IssueFields{Summary: fmt.Sprintf("test %d", i)}})
+		}
+		fake = newFakeJira(issues...)
+		server = httptest.NewServer(fake)
+		info = &jira_helper.JiraInfo{BaseURL: server.URL, Project: "E2E", Username: "e2e", IssueType: "Bug"}
+
+		jira_helper.SetSearchPageSize(3)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		jira_helper.SetSearchPageSize(100)
+	})
+
+	It("pages through all open issues", func() {
+		openIssues, err := jira_helper.GetOpenE2EJiraIssue(context.TODO(), info)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(openIssues).To(HaveLen(7))
+		Expect(openIssues[6].Key).To(Equal("E2E-7"))
+		Expect(fake.searches).To(HaveLen(3))
+	})
+
+	It("pages through all open issues when jira returns fewer issues than requested", func() {
+		fake.maxPageSize = 2
+		openIssues, err := jira_helper.GetOpenE2EJiraIssue(context.TODO(), info)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(openIssues).To(HaveLen(7))
+		Expect(fake.searches).To(HaveLen(4))
+	})
+
+	It("requests only the fields needed to match issues", func() {
+		_, err := jira_helper.GetOpenE2EJiraIssue(context.TODO(), info)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fake.searchFields).To(HaveEach("summary,description,labels"))
+	})
+
+	It("narrows search by project and component", func() {
+		info.Component = "networking"
+		_, err := jira_helper.GetOpenE2EJiraIssue(context.TODO(), info)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fake.searches[0]).To(Equal(`project = "E2E" and reporter = "e2e" and type = "Bug" and component = "networking"`))
+	})
+})
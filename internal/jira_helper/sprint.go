@@ -0,0 +1,110 @@
+package jira_helper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/andygrunwald/go-jira"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+)
+
+// SprintPolicy defines which sprint, if any, issues are moved to
+type SprintPolicy string
+
+const (
+	// SprintPolicyNone does not move issues to any sprint. No board is needed.
+	SprintPolicyNone = SprintPolicy("none")
+	// SprintPolicyActive moves filed and commented issues to the active sprint of the board
+	SprintPolicyActive = SprintPolicy("active")
+	// SprintPolicyNamed moves filed and commented issues to the active or future sprint of
+	// the board named JiraInfo.SprintName
+	SprintPolicyNamed = SprintPolicy("named")
+	// SprintPolicyNewIssues moves only filed issues to the active sprint of the board. Issues
+	// which are commented are left where they are.
+	SprintPolicyNewIssues = SprintPolicy("new-issues")
+)
+
+// verifySprintPolicy verifies sprint policy is supported and has what it needs
+func verifySprintPolicy(info *JiraInfo) error {
+	switch info.SprintPolicy {
+	case SprintPolicyNone:
+		return nil
+	case "", SprintPolicyActive, SprintPolicyNewIssues:
+	case SprintPolicyNamed:
+		if info.SprintName == "" {
+			return fmt.Errorf("sprint policy %s requires a sprint name", info.SprintPolicy)
+		}
+	default:
+		return fmt.Errorf("unsupported sprint policy %q", info.SprintPolicy)
+	}
+
+	if info.Board == "" {
+		return fmt.Errorf("sprint policy %s requires a board", info.SprintPolicy)
+	}
+	return nil
+}
+
+// getTargetSprint returns the sprint issues are moved to according to info.SprintPolicy.
+// Returns nil if issues must not be moved to any sprint or, for policies using the active
+// sprint, if the board has no active sprint (or does not support sprints, like Kanban boards).
+func getTargetSprint(ctx context.Context, jiraClient *jira.Client, projectKey string,
+	info *JiraInfo) (*jira.Sprint, error) {
+	if info.SprintPolicy == SprintPolicyNone {
+		return nil, nil
+	}
+
+	board, err := getJiraBoard(ctx, jiraClient, projectKey, info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jira board: %w", err)
+	}
+	if board == nil {
+		return nil, fmt.Errorf("failed to get jira board %s", info.Board)
+	}
+
+	if info.SprintPolicy == SprintPolicyNamed {
+		return getJiraNamedSprint(ctx, jiraClient, board.ID, info.SprintName)
+	}
+
+	sprint, err := getJiraActiveSprint(ctx, jiraClient, fmt.Sprintf("%d", board.ID))
+	if err != nil || sprint == nil {
+		utils.Byf(fmt.Sprintf("No active sprint found for board %s (err: %v). Issues will not be moved to any sprint",
+			info.Board, err))
+		return nil, nil
+	}
+	return sprint, nil
+}
+
+// getJiraNamedSprint returns the active or future sprint with name sprintName in board.
+// Returns an error if no such sprint exists.
+func getJiraNamedSprint(ctx context.Context, jiraClient *jira.Client, boardID int,
+	sprintName string) (*jira.Sprint, error) {
+	options := &jira.GetAllSprintsOptions{State: "active,future"}
+	for {
+		sprints, resp, err := jiraClient.Board.GetAllSprintsWithOptionsWithContext(ctx, boardID, options)
+		if err != nil {
+			utils.Byf(fmt.Sprintf("Failed to get sprints. Error: %v. Resp %s", err, getResponseBody(resp)))
+			return nil, fmt.Errorf("failed to get sprints of board %d: %w", boardID, err)
+		}
+
+		for i := range sprints.Values {
+			if sprints.Values[i].Name == sprintName {
+				return &sprints.Values[i], nil
+			}
+		}
+
+		if sprints.IsLast || len(sprints.Values) == 0 {
+			return nil, fmt.Errorf("no active or future sprint named %s found", sprintName)
+		}
+		options.StartAt += len(sprints.Values)
+	}
+}
+
+// shouldMoveToSprint returns true if, according to info.SprintPolicy, an issue must be
+// moved to sprint. newIssue indicates whether issue has just been filed.
+func shouldMoveToSprint(sprint *jira.Sprint, newIssue bool, info *JiraInfo) bool {
+	if sprint == nil {
+		return false
+	}
+	return newIssue || info.SprintPolicy != SprintPolicyNewIssues
+}
@@ -0,0 +1,121 @@
+package jira_helper_test
+
+import (
+	"context"
+	"net/http/httptest"
+
+	"github.com/andygrunwald/go-jira"
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/jira_helper"
+)
+
+var _ = Describe("Sprint policy", func() {
+	var fake *fakeJira
+	var server *httptest.Server
+	var info *jira_helper.JiraInfo
+	var report *ginkgoTypes.Report
+
+	BeforeEach(func() {
+		existing := getFailedSpecReport()
+		existing.LeafNodeText = "return sorted list"
+		fake = newFakeJira(jira.Issue{ID: "1", Key: "E2E-1", Fields: &jira.IssueFields{Summary: "return sorted list"}})
+		server = httptest.NewServer(fake)
+		info = &jira_helper.JiraInfo{BaseURL: server.URL, Project: "E2E", Board: "e2e board", Username: "e2e",
+			IssueType: "Bug", Priority: "P1"}
+		report = &ginkgoTypes.Report{SpecReports: ginkgoTypes.SpecReports{getFailedSpecReport(), existing}}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("active moves new and commented issues to active sprint", func() {
+		info.SprintPolicy = jira_helper.SprintPolicyActive
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 12, info)).To(Succeed())
+		Expect(fake.sprintIssues["7"]).To(ConsistOf("101", "1"))
+	})
+
+	It("new-issues moves only new issues to active sprint", func() {
+		info.SprintPolicy = jira_helper.SprintPolicyNewIssues
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 12, info)).To(Succeed())
+		Expect(fake.sprintIssues["7"]).To(ConsistOf("101"))
+		Expect(fake.comments).To(HaveKey("1"))
+	})
+
+	It("named moves issues to the sprint with that name", func() {
+		fake.sprints = append(fake.sprints,
+			jira.Sprint{ID: 8, Name: "Hardening", State: "closed"},
+			jira.Sprint{ID: 9, Name: "Hardening", State: "future"})
+		info.SprintPolicy = jira_helper.SprintPolicyNamed
+		info.SprintName = "Hardening"
+		Expect(jira_helper.VerifyInfo(context.TODO(), info)).To(Succeed())
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 12, info)).To(Succeed())
+		Expect(fake.sprintIssues).To(HaveLen(1))
+		Expect(fake.sprintIssues["9"]).To(ConsistOf("101", "1"))
+	})
+
+	It("named fails when no sprint has that name", func() {
+		info.SprintPolicy = jira_helper.SprintPolicyNamed
+		info.SprintName = "Hardening"
+		Expect(jira_helper.VerifyInfo(context.TODO(), info)).To(MatchError(ContainSubstring("Hardening")))
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 12, info)).ToNot(Succeed())
+		Expect(fake.created).To(BeEmpty())
+	})
+
+	It("none does not need a board and does not move issues", func() {
+		info.SprintPolicy = jira_helper.SprintPolicyNone
+		info.Board = ""
+		Expect(jira_helper.VerifyInfo(context.TODO(), info)).To(Succeed())
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 12, info)).To(Succeed())
+		Expect(fake.created).To(HaveLen(1))
+		Expect(fake.sprintIssues).To(BeEmpty())
+	})
+
+	It("files issues without moving them when board has no active sprint", func() {
+		fake.sprints[0].State = "closed"
+		fake.sprints[0].StartDate, fake.sprints[0].EndDate = nil, nil
+		Expect(jira_helper.VerifyInfo(context.TODO(), info)).To(Succeed())
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 12, info)).To(Succeed())
+		Expect(fake.created).To(HaveLen(1))
+		Expect(fake.sprintIssues).To(BeEmpty())
+	})
+
+	It("files issues without moving them when board does not support sprints", func() {
+		fake.kanban = true
+		Expect(jira_helper.VerifyInfo(context.TODO(), info)).To(Succeed())
+		Expect(jira_helper.FileJiraIssuesForFailedTests(context.TODO(), report, 12, info)).To(Succeed())
+		Expect(fake.created).To(HaveLen(1))
+		Expect(fake.sprintIssues).To(BeEmpty())
+	})
+
+	It("VerifyInfo reports sprint policies needing a board or a sprint name", func() {
+		info.Board = ""
+		Expect(jira_helper.VerifyInfo(context.TODO(), info)).To(MatchError(ContainSubstring("requires a board")))
+
+		info.Board = "e2e board"
+		info.SprintPolicy = jira_helper.SprintPolicyNamed
+		Expect(jira_helper.VerifyInfo(context.TODO(), info)).To(MatchError(ContainSubstring("requires a sprint name")))
+
+		info.SprintPolicy = "sometimes"
+		Expect(jira_helper.VerifyInfo(context.TODO(), info)).To(MatchError(ContainSubstring("unsupported sprint policy")))
+	})
+})
+
+var _ = Describe("Unreachable jira", func() {
+	It("reports an error, instead of panicking, when request cannot be sent", func() {
+		server := httptest.NewServer(newFakeJira())
+		server.Close()
+		info := &jira_helper.JiraInfo{BaseURL: server.URL, Project: "E2E", Board: "e2e board"}
+		jiraClient, err := jira.NewClient(nil, server.URL)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = jira_helper.GetJiraProject(context.TODO(), jiraClient, info)
+		Expect(err).To(HaveOccurred())
+		_, err = jira_helper.GetJiraBoard(context.TODO(), jiraClient, "E2E", info)
+		Expect(err).To(HaveOccurred())
+		Expect(jira_helper.MoveIssueToSprint(context.TODO(), jiraClient, 7, "E2E-1")).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,213 @@
+package ownership_helper
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	"gopkg.in/yaml.v2"
+)
+
+// Owner is the owner of a set of tests
+type Owner struct {
+	// JiraAssignee is the jira user issues of failed tests are assigned to
+	JiraAssignee string `yaml:"jiraAssignee,omitempty"`
+	// JiraComponent is the component of issues filed for failed tests
+	JiraComponent string `yaml:"jiraComponent,omitempty"`
+	// Slack contains the Slack user ids (U...) and user group ids (S...) to mention
+	Slack []string `yaml:"slack,omitempty"`
+	// Webex contains the emails of the Webex users to mention
+	Webex []string `yaml:"webex,omitempty"`
+}
+
+// Rule maps tests to an owner. A test matches a rule if it matches any of
+// paths, containers or labels.
+type Rule struct {
+	// Paths are globs matched against the file a spec is defined in. As in CODEOWNERS,
+	// * matches anything but /, ** matches anything, a pattern ending with / matches
+	// everything in a directory and patterns are matched against the end of the path.
+	Paths []string `yaml:"paths,omitempty"`
+	// Containers are regular expressions matched against the text of each container
+	Containers []string `yaml:"containers,omitempty"`
+	// Labels are matched against spec labels
+	Labels []string `yaml:"labels,omitempty"`
+
+	Owner `yaml:",inline"`
+}
+
+// file is the format of an ownership file
+type file struct {
+	Owners []Rule `yaml:"owners"`
+}
+
+// rule is a Rule with compiled patterns
+type rule struct {
+	paths      []*regexp.Regexp
+	containers []*regexp.Regexp
+	labels     map[string]bool
+	owner      Owner
+}
+
+// Ownership maps tests to owners
+type Ownership struct {
+	rules []rule
+}
+
+// LoadFile loads the ownership file at path. See Load.
+func LoadFile(path string) (*Ownership, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ownership file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	o, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("ownership file %s: %w", path, err)
+	}
+	return o, nil
+}
+
+// Load reads an ownership file (YAML or JSON)
+//
+//	owners:
+//	- paths: ["test/e2e/network/"]
+//	  containers: ["^Networking"]
+//	  labels: ["network"]
+//	  jiraAssignee: alice
+//	  jiraComponent: Networking
+//	  slack: ["S0123ABCD"]
+//	  webex: ["alice@org.com"]
+//
+// When more than one rule matches a test, the last one wins (as in CODEOWNERS).
+func Load(r io.Reader) (*Ownership, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ownership: %w", err)
+	}
+
+	f := &file{}
+	if err := yaml.UnmarshalStrict(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse ownership: %w", err)
+	}
+
+	o := &Ownership{rules: make([]rule, len(f.Owners))}
+	for i := range f.Owners {
+		if o.rules[i], err = compileRule(&f.Owners[i]); err != nil {
+			return nil, fmt.Errorf("owners[%d]: %w", i, err)
+		}
+	}
+	return o, nil
+}
+
+// GetOwner returns the owner of a test. Returns nil if no rule matches the test.
+func (o *Ownership) GetOwner(specReport *ginkgoTypes.SpecReport) *Owner {
+	if o == nil {
+		return nil
+	}
+
+	for i := len(o.rules) - 1; i >= 0; i-- {
+		if o.rules[i].matches(specReport) {
+			return &o.rules[i].owner
+		}
+	}
+	return nil
+}
+
+// Components returns all jira components owners are mapped to
+func (o *Ownership) Components() []string {
+	if o == nil {
+		return nil
+	}
+
+	components := make([]string, 0)
+	seen := make(map[string]bool)
+	for i := range o.rules {
+		component := o.rules[i].owner.JiraComponent
+		if component != "" && !seen[component] {
+			seen[component] = true
+			components = append(components, component)
+		}
+	}
+	return components
+}
+
+func compileRule(r *Rule) (rule, error) {
+	if len(r.Paths) == 0 && len(r.Containers) == 0 && len(r.Labels) == 0 {
+		return rule{}, fmt.Errorf("at least one of paths, containers or labels is required")
+	}
+
+	compiled := rule{owner: r.Owner, labels: make(map[string]bool)}
+	for _, pattern := range r.Paths {
+		compiled.paths = append(compiled.paths, globToRegexp(pattern))
+	}
+	for _, expr := range r.Containers {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return rule{}, fmt.Errorf("invalid container regular expression %q: %w", expr, err)
+		}
+		compiled.containers = append(compiled.containers, re)
+	}
+	for _, label := range r.Labels {
+		compiled.labels[label] = true
+	}
+	return compiled, nil
+}
+
+func (r *rule) matches(specReport *ginkgoTypes.SpecReport) bool {
+	fileName := specReport.LeafNodeLocation.FileName
+	for i := range r.paths {
+		if fileName != "" && r.paths[i].MatchString(fileName) {
+			return true
+		}
+	}
+
+	for i := range r.containers {
+		for _, text := range specReport.ContainerHierarchyTexts {
+			if r.containers[i].MatchString(text) {
+				return true
+			}
+		}
+	}
+
+	for _, label := range specReport.Labels() {
+		if r.labels[label] {
+			return true
+		}
+	}
+	return false
+}
+
+// globToRegexp returns the regular expression corresponding to a CODEOWNERS-like glob
+func globToRegexp(pattern string) *regexp.Regexp {
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var expr strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		}
+	}
+	if strings.HasSuffix(pattern, "/") {
+		// everything in the directory
+		expr.WriteString(".*")
+	}
+
+	prefix := "(^|/)"
+	if anchored {
+		prefix = "^/?"
+	}
+	return regexp.MustCompile(prefix + expr.String() + "$")
+}
@@ -0,0 +1,13 @@
+package ownership_helper_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestOwnershipHelper(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OwnershipHelper Suite")
+}
@@ -0,0 +1,120 @@
+package ownership_helper_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ownership_helper"
+)
+
+const ownership = `
+owners:
+- paths: ["test/e2e/"]
+  jiraAssignee: e2e-team
+  jiraComponent: E2E
+  slack: ["S0E2E"]
+- paths: ["network/*_test.go"]
+  containers: ["^Networking"]
+  jiraAssignee: alice
+  jiraComponent: Networking
+  slack: ["U0ALICE"]
+  webex: ["alice@org.com"]
+- labels: ["storage"]
+  jiraAssignee: bob
+  webex: ["bob@org.com"]
+`
+
+func getSpecReport(fileName string, containers []string, labels ...string) *ginkgoTypes.SpecReport {
+	return &ginkgoTypes.SpecReport{
+		ContainerHierarchyTexts: containers,
+		LeafNodeText:            "works",
+		LeafNodeLabels:          labels,
+		LeafNodeLocation:        ginkgoTypes.CodeLocation{FileName: fileName, LineNumber: 10},
+	}
+}
+
+var _ = Describe("Ownership", func() {
+	var o *ownership_helper.Ownership
+
+	BeforeEach(func() {
+		var err error
+		o, err = ownership_helper.Load(strings.NewReader(ownership))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("matches directories and globs against the end of spec file path", func() {
+		owner := o.GetOwner(getSpecReport("/home/ci/repo/test/e2e/list_test.go", nil))
+		Expect(owner).ToNot(BeNil())
+		Expect(owner.JiraAssignee).To(Equal("e2e-team"))
+
+		// last matching rule wins
+		owner = o.GetOwner(getSpecReport("/home/ci/repo/test/e2e/network/dns_test.go", nil))
+		Expect(owner).ToNot(BeNil())
+		Expect(owner.JiraAssignee).To(Equal("alice"))
+
+		// * does not match /
+		owner = o.GetOwner(getSpecReport("/home/ci/repo/test/e2e/network/ipv6/dns_test.go", nil))
+		Expect(owner.JiraAssignee).To(Equal("e2e-team"))
+
+		Expect(o.GetOwner(getSpecReport("/home/ci/repo/mytest/e2e/list_test.go", nil))).To(BeNil())
+	})
+
+	It("matches container texts and labels", func() {
+		owner := o.GetOwner(getSpecReport("/src/suite_test.go", []string{"Networking DNS", "IPv4"}))
+		Expect(owner).ToNot(BeNil())
+		Expect(owner.Slack).To(ConsistOf("U0ALICE"))
+
+		Expect(o.GetOwner(getSpecReport("/src/suite_test.go", []string{"Cluster Networking"}))).To(BeNil())
+
+		owner = o.GetOwner(getSpecReport("/src/test/e2e/pvc_test.go", []string{"Networking"}, "storage"))
+		Expect(owner).ToNot(BeNil())
+		Expect(owner.JiraAssignee).To(Equal("bob"))
+		Expect(owner.JiraComponent).To(BeEmpty())
+	})
+
+	It("supports ** and anchored patterns", func() {
+		o, err := ownership_helper.Load(strings.NewReader(`
+owners:
+- paths: ["/pkg/**/upgrade_test.go"]
+  jiraAssignee: carol
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(o.GetOwner(getSpecReport("pkg/a/b/upgrade_test.go", nil))).ToNot(BeNil())
+		Expect(o.GetOwner(getSpecReport("src/pkg/a/upgrade_test.go", nil))).To(BeNil())
+	})
+
+	It("returns components owners are mapped to", func() {
+		Expect(o.Components()).To(Equal([]string{"E2E", "Networking"}))
+
+		var none *ownership_helper.Ownership
+		Expect(none.Components()).To(BeEmpty())
+		Expect(none.GetOwner(getSpecReport("/src/list_test.go", nil))).To(BeNil())
+	})
+
+	It("reports invalid ownership files", func() {
+		_, err := ownership_helper.Load(strings.NewReader("owners:\n- jiraAssignee: alice\n"))
+		Expect(err).To(MatchError(ContainSubstring("owners[0]")))
+
+		_, err = ownership_helper.Load(strings.NewReader("owners:\n- containers: [\"(\"]\n"))
+		Expect(err).To(MatchError(ContainSubstring("invalid container regular expression")))
+
+		_, err = ownership_helper.Load(strings.NewReader("owners:\n- labels: [a]\n  assignee: alice\n"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("LoadFile reads YAML and JSON files", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "owners.json")
+		Expect(os.WriteFile(path, []byte(`{"owners": [{"labels": ["storage"], "slack": ["U0BOB"]}]}`), 0600)).To(Succeed())
+		o, err := ownership_helper.LoadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(o.GetOwner(getSpecReport("", nil, "storage")).Slack).To(ConsistOf("U0BOB"))
+
+		_, err = ownership_helper.LoadFile(filepath.Join(GinkgoT().TempDir(), "missing.yaml"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,155 @@
+package slack_helper
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+// Slack Block Kit limits
+const (
+	maxBlocksPerMessage  = 50
+	maxSectionTextLength = 3000
+	maxHeaderTextLength  = 150
+)
+
+// codeFence starts and ends a code block in Slack mrkdwn text
+const codeFence = "```"
+
+// Message is a message sent as Block Kit blocks: a header, a section per entry
+// of Sections and a context block. Replies, if any, are posted in the message thread.
+// File, if any, is uploaded and linked from the message.
+type Message struct {
+	Header   string   // plain text header, e.g. suite name, run id and pass/fail counts
+	Sections []string // mrkdwn text of each section, e.g. one per failed test
+	Context  string   // mrkdwn text of the context block, e.g. run duration
+	Text     string   // plain text used in notifications and by clients which cannot show blocks
+	Replies  []Reply  // replies posted in the thread of the message
+	File     *File    // file uploaded along with the message
+}
+
+// Reply is a reply, sent as Block Kit sections, posted in the thread of a Message
+type Reply struct {
+	Sections  []string // mrkdwn text of each section
+	Text      string   // plain text used in notifications and by clients which cannot show blocks
+	Broadcast bool     // also send the reply to the channel
+}
+
+// Escape escapes the characters having a special meaning in Slack mrkdwn text
+func Escape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// EscapeCode escapes text, as Escape does, to be shown in a code block: code fences in
+// text are broken with zero width spaces so they cannot end the code block.
+func EscapeCode(text string) string {
+	text = Escape(text)
+	for strings.Contains(text, codeFence) {
+		text = strings.ReplaceAll(text, codeFence, "`\u200b`\u200b`")
+	}
+	return text
+}
+
+// getBlocks returns the blocks of msg, split into as many messages as needed to fit
+// Slack limits. Header is in the first message, context in the last one. Sections
+// longer than the allowed length are split (at line boundaries, when possible).
+func getBlocks(msg *Message) [][]slack.Block {
+	messages := make([][]slack.Block, 0)
+	current := make([]slack.Block, 0, maxBlocksPerMessage)
+	add := func(block slack.Block) {
+		if len(current) == maxBlocksPerMessage {
+			messages = append(messages, current)
+			current = make([]slack.Block, 0, maxBlocksPerMessage)
+		}
+		current = append(current, block)
+	}
+
+	if msg.Header != "" {
+		add(slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType,
+			truncate(msg.Header, maxHeaderTextLength), false, false)))
+	}
+
+	for i := range msg.Sections {
+		for _, text := range splitText(msg.Sections[i], maxSectionTextLength) {
+			add(slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil))
+		}
+	}
+
+	if msg.Context != "" {
+		add(slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, msg.Context, false, false)))
+	}
+
+	if len(current) != 0 {
+		messages = append(messages, current)
+	}
+	return messages
+}
+
+// splitText splits text in chunks no longer than maxLength bytes. Text is split at
+// line boundaries unless a single line is longer than maxLength. A code block split
+// across chunks is closed at the end of a chunk and reopened at the beginning of the
+// next one, so that every chunk renders on its own.
+func splitText(text string, maxLength int) []string {
+	if !strings.Contains(text, codeFence) {
+		return splitLines(text, maxLength)
+	}
+
+	// leave room to close and reopen a code block
+	chunks := splitLines(text, maxLength-2*len(codeFence))
+	open := false
+	for i := range chunks {
+		if open {
+			chunks[i] = codeFence + chunks[i]
+		}
+		open = strings.Count(chunks[i], codeFence)%2 == 1
+		if open {
+			chunks[i] += codeFence
+		}
+	}
+	return chunks
+}
+
+// splitLines splits text in chunks no longer than maxLength bytes. Text is split at
+// line boundaries unless a single line is longer than maxLength.
+func splitLines(text string, maxLength int) []string {
+	chunks := make([]string, 0)
+	current := ""
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if len(current)+len(line) <= maxLength {
+			current += line
+			continue
+		}
+		if current != "" {
+			chunks = append(chunks, current)
+			current = ""
+		}
+		for len(line) > maxLength {
+			head := truncate(line, maxLength)
+			// Do not split a code fence
+			for len(head) > 1 && strings.HasSuffix(head, "`") && strings.HasPrefix(line[len(head):], "`") {
+				head = head[:len(head)-1]
+			}
+			chunks = append(chunks, head)
+			line = line[len(head):]
+		}
+		current = line
+	}
+	if current != "" || len(chunks) == 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// truncate returns the longest prefix of text no longer than maxLength bytes
+// which does not break a UTF-8 character
+func truncate(text string, maxLength int) string {
+	if len(text) <= maxLength {
+		return text
+	}
+	end := maxLength
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return text[:end]
+}
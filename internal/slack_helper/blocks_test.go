@@ -0,0 +1,95 @@
+package slack_helper_test
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/slack_helper"
+)
+
+var _ = Describe("Block Kit messages", func() {
+	It("puts header first and context last", func() {
+		messages := slack_helper.GetBlocks(&slack_helper.Message{
+			Header:   "e2e run 12: 1 failed, 3 passed",
+			Sections: []string{"*failed test*"},
+			Context:  "Duration: 1m0s",
+		})
+		Expect(messages).To(HaveLen(1))
+		Expect(messages[0]).To(HaveLen(3))
+		Expect(messages[0][0].BlockType()).To(Equal(slack.MBTHeader))
+		Expect(messages[0][1].BlockType()).To(Equal(slack.MBTSection))
+		Expect(messages[0][2].BlockType()).To(Equal(slack.MBTContext))
+	})
+
+	It("splits messages with more than 50 blocks", func() {
+		sections := make([]string, 120)
+		for i := range sections {
+			sections[i] = fmt.Sprintf("test %d", i)
+		}
+		messages := slack_helper.GetBlocks(&slack_helper.Message{Header: "header", Sections: sections, Context: "context"})
+		Expect(messages).To(HaveLen(3))
+		Expect(messages[0]).To(HaveLen(50))
+		Expect(messages[1]).To(HaveLen(50))
+		Expect(messages[2]).To(HaveLen(22))
+		Expect(messages[0][0].BlockType()).To(Equal(slack.MBTHeader))
+		Expect(messages[2][21].BlockType()).To(Equal(slack.MBTContext))
+		Expect(messages[1][0].(*slack.SectionBlock).Text.Text).To(Equal("test 49"))
+	})
+
+	It("splits sections longer than 3000 characters at line boundaries", func() {
+		line := strings.Repeat("a", 999) + "\n"
+		messages := slack_helper.GetBlocks(&slack_helper.Message{Sections: []string{strings.Repeat(line, 4)}})
+		Expect(messages).To(HaveLen(1))
+		Expect(messages[0]).To(HaveLen(2))
+		Expect(messages[0][0].(*slack.SectionBlock).Text.Text).To(Equal(strings.Repeat(line, 3)))
+		Expect(messages[0][1].(*slack.SectionBlock).Text.Text).To(Equal(line))
+	})
+
+	It("splits lines longer than allowed without breaking characters", func() {
+		chunks := slack_helper.SplitText(strings.Repeat("é", 5), 4)
+		Expect(chunks).To(Equal([]string{"éé", "éé", "é"}))
+	})
+
+	It("closes and reopens code blocks split across chunks", func() {
+		text := "*Stack trace*\n```" + strings.Repeat("frame\n", 10) + "```\nafter"
+		chunks := slack_helper.SplitText(text, 30)
+		Expect(len(chunks)).To(BeNumerically(">", 2))
+		for i := range chunks {
+			Expect(len(chunks[i])).To(BeNumerically("<=", 30))
+			Expect(strings.Count(chunks[i], "```")%2).To(BeZero(), chunks[i])
+		}
+		Expect(chunks[0]).To(HavePrefix("*Stack trace*\n```"))
+		Expect(chunks[1]).To(HavePrefix("```frame"))
+		Expect(chunks[len(chunks)-1]).To(HaveSuffix("```\nafter"))
+		Expect(strings.ReplaceAll(strings.Join(chunks, ""), "``````", "")).To(Equal(text))
+	})
+
+	It("does not split code fences within long lines", func() {
+		chunks := slack_helper.SplitText("```"+strings.Repeat("a", 20)+"```", 16)
+		for i := range chunks {
+			Expect(chunks[i]).To(HavePrefix("```"))
+			Expect(chunks[i]).To(HaveSuffix("```"))
+			Expect(len(chunks[i])).To(BeNumerically("<=", 16))
+		}
+	})
+
+	It("truncates long headers", func() {
+		messages := slack_helper.GetBlocks(&slack_helper.Message{Header: strings.Repeat("h", 200)})
+		Expect(messages[0][0].(*slack.HeaderBlock).Text.Text).To(HaveLen(150))
+	})
+
+	It("escapes mrkdwn control characters", func() {
+		Expect(slack_helper.Escape("a < b && c > d")).To(Equal("a &lt; b &amp;&amp; c &gt; d"))
+	})
+
+	It("escapes code fences in code blocks", func() {
+		Expect(slack_helper.EscapeCode("a < b ```go")).To(Equal("a &lt; b `\u200b`\u200b`go"))
+		for _, text := range []string{"````", "`````", "``````"} {
+			Expect(slack_helper.EscapeCode(text)).ToNot(ContainSubstring("```"))
+		}
+	})
+})
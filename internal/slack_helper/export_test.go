@@ -0,0 +1,12 @@
+package slack_helper
+
+var (
+	GetBlocks = getBlocks
+	SplitText = splitText
+)
+
+func ResetChannelIDs() {
+	channelIDsMu.Lock()
+	defer channelIDsMu.Unlock()
+	channelIDs = make(map[string]string)
+}
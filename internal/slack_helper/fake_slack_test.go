@@ -0,0 +1,171 @@
+package slack_helper_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// postedMessage is a message posted with chat.postMessage or to the incoming webhook
+type postedMessage struct {
+	Channel        string
+	Text           string
+	Blocks         []json.RawMessage
+	ThreadTS       string
+	ReplyBroadcast bool
+}
+
+// fakeSlack is a minimal Slack API (under /api/) and incoming webhook (/webhook) server
+type fakeSlack struct {
+	token    string          // valid auth token
+	channels []slack.Channel // channels returned by conversations.list
+	pageSize int             // channels per conversations.list page
+
+	requests []string        // API methods called, in order
+	posted   []postedMessage // messages posted with chat.postMessage
+	webhook  []postedMessage // messages posted to the incoming webhook
+
+	rateLimited map[int]bool // chat.postMessage calls (1 based) rejected with 429
+	postCalls   int          // chat.postMessage calls, rejected ones included
+
+	url         string            // server URL, used to build upload URLs
+	uploadError string            // if set, error returned by files.getUploadURLExternal
+	uploads     map[string][]byte // uploaded content per file id
+	shared      map[string]string // channel id per file id whose upload was completed
+}
+
+func newFakeSlack(channels ...string) *fakeSlack {
+	f := &fakeSlack{token: "xoxb-e2e", pageSize: 2, uploads: make(map[string][]byte), shared: make(map[string]string)}
+	for i, name := range channels {
+		channel := slack.Channel{}
+		channel.ID = fmt.Sprintf("C%03d", i)
+		channel.Name = name
+		f.channels = append(f.channels, channel)
+	}
+	return f
+}
+
+func (f *fakeSlack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/webhook" {
+		body, _ := io.ReadAll(r.Body)
+		msg := struct {
+			Text   string            `json:"text"`
+			Blocks []json.RawMessage `json:"blocks"`
+		}{}
+		if err := json.Unmarshal(body, &msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.webhook = append(f.webhook, postedMessage{Text: msg.Text, Blocks: msg.Blocks})
+		fmt.Fprint(w, "ok")
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/upload/") {
+		f.uploads[strings.TrimPrefix(r.URL.Path, "/upload/")], _ = io.ReadAll(r.Body)
+		fmt.Fprint(w, "OK")
+		return
+	}
+
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+	f.requests = append(f.requests, method)
+	_ = r.ParseForm()
+	if r.FormValue("token") != f.token && r.Header.Get("Authorization") != "Bearer "+f.token {
+		writeJSON(w, map[string]interface{}{"ok": false, "error": "invalid_auth"})
+		return
+	}
+
+	switch method {
+	case "auth.test":
+		writeJSON(w, map[string]interface{}{"ok": true, "user_id": "U0BOT"})
+	case "conversations.list":
+		f.listConversations(w, r)
+	case "chat.postMessage":
+		f.postCalls++
+		if f.rateLimited[f.postCalls] {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		msg := postedMessage{
+			Channel:        r.FormValue("channel"),
+			Text:           r.FormValue("text"),
+			ThreadTS:       r.FormValue("thread_ts"),
+			ReplyBroadcast: r.FormValue("reply_broadcast") == "true",
+		}
+		_ = json.Unmarshal([]byte(r.FormValue("blocks")), &msg.Blocks)
+		f.posted = append(f.posted, msg)
+		writeJSON(w, map[string]interface{}{"ok": true, "channel": msg.Channel,
+			"ts": fmt.Sprintf("1700000000.%06d", len(f.posted))})
+	case "files.getUploadURLExternal":
+		if f.uploadError != "" {
+			writeJSON(w, map[string]interface{}{"ok": false, "error": f.uploadError})
+			return
+		}
+		id := fmt.Sprintf("F%03d", len(f.uploads)+1)
+		f.uploads[id] = nil
+		writeJSON(w, map[string]interface{}{"ok": true, "file_id": id, "upload_url": f.url + "/upload/" + id})
+	case "files.completeUploadExternal":
+		f.completeUpload(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// completeUpload shares uploaded files in the requested channel
+func (f *fakeSlack) completeUpload(w http.ResponseWriter, r *http.Request) {
+	files := make([]map[string]string, 0)
+	if err := json.Unmarshal([]byte(r.FormValue("files")), &files); err != nil {
+		writeJSON(w, map[string]interface{}{"ok": false, "error": "invalid_arguments"})
+		return
+	}
+
+	completed := make([]map[string]string, 0)
+	for _, file := range files {
+		if _, ok := f.uploads[file["id"]]; !ok {
+			writeJSON(w, map[string]interface{}{"ok": false, "error": "file_not_found"})
+			return
+		}
+		f.shared[file["id"]] = r.FormValue("channel_id")
+		completed = append(completed, map[string]string{"id": file["id"], "title": file["title"],
+			"permalink": "https://e2e.slack.com/files/U0BOT/" + file["id"]})
+	}
+	writeJSON(w, map[string]interface{}{"ok": true, "files": completed})
+}
+
+// listConversations returns f.pageSize channels, of the requested types, starting at the
+// index in cursor
+func (f *fakeSlack) listConversations(w http.ResponseWriter, r *http.Request) {
+	channels := make([]slack.Channel, 0)
+	types := r.FormValue("types")
+	for i := range f.channels {
+		if !f.channels[i].IsPrivate || strings.Contains(types, "private_channel") {
+			channels = append(channels, f.channels[i])
+		}
+	}
+
+	start := 0
+	if cursor := r.FormValue("cursor"); cursor != "" {
+		fmt.Sscanf(cursor, "page-%d", &start)
+	}
+	end := start + f.pageSize
+	if end > len(channels) {
+		end = len(channels)
+	}
+
+	nextCursor := ""
+	if end < len(channels) {
+		nextCursor = fmt.Sprintf("page-%d", end)
+	}
+	writeJSON(w, map[string]interface{}{"ok": true, "channels": channels[start:end],
+		"response_metadata": map[string]string{"next_cursor": nextCursor}})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
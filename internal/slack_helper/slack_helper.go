@@ -2,21 +2,39 @@ package slack_helper
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
 	"github.com/slack-go/slack"
 )
 
 type SlackInfo struct {
-	AuthToken string // slack auth token
-	Channel   string // slack channel name
-	DryRun    bool   // indicates if this is a dryRun
+	AuthToken string // slack auth token. Not needed with WebhookURL
+	Channel   string // slack channel name (or ID). Not needed with ChannelID or WebhookURL
+	ChannelID string // slack channel ID. If set, channel is not looked up by name
+	// WebhookURL is the URL of an incoming webhook. If set, messages are posted to it and
+	// neither auth token nor channel are needed.
+	WebhookURL string
+	// APIURL is the slack API URL. Default to https://slack.com/api/
+	APIURL string
+	DryRun bool // indicates if this is a dryRun
 }
 
-// VerifyInfo verifies provided info (slack authorization token and channel name) are correct
+// VerifyInfo verifies provided info (slack authorization token and channel name) are correct.
+// With a webhook URL, only the URL is verified: incoming webhooks cannot be verified without
+// posting a message.
 func VerifyInfo(ctx context.Context, info *SlackInfo) error {
-	api := slack.New(info.AuthToken)
+	if info.WebhookURL != "" {
+		return verifyWebhookURL(info.WebhookURL)
+	}
+
+	api := getSlackClient(info)
 	if api == nil {
 		return fmt.Errorf("failed to get slack client")
 	}
@@ -33,55 +51,259 @@ func VerifyInfo(ctx context.Context, info *SlackInfo) error {
 }
 
 // SendSlackMessage sends slack message to specified room.
-// text is a markdown message
-func SendSlackMessage(info *SlackInfo, text string) {
-	utils.Byf(fmt.Sprintf("Get channel ID %s", info.Channel))
-	api := slack.New(info.AuthToken)
-	if api == nil {
-		utils.Byf("failed to get slack client")
+// Message is sent as Block Kit blocks, split into as many messages as needed
+// to fit Slack limits. Message replies are then posted in the thread of the
+// (first) message. Incoming webhooks do not return the message timestamp, so
+// replies sent to a webhook are posted as separate messages.
+// Message file, if any, is uploaded to the channel first and linked from the message.
+// Files cannot be uploaded to incoming webhooks.
+func SendSlackMessage(info *SlackInfo, msg *Message) error {
+	var api *slack.Client
+	var channelID string
+	if info.WebhookURL == "" {
+		utils.Byf(fmt.Sprintf("Get channel ID %s", info.Channel))
+		api = getSlackClient(info)
+		if api == nil {
+			utils.Byf("failed to get slack client")
+			return fmt.Errorf("failed to get slack client")
+		}
+
+		var err error
+		channelID, err = getChannelID(info)
+		if err != nil {
+			utils.Byf(fmt.Sprintf("failed to get channel %s", info.Channel))
+			return err
+		}
+	}
+
+	utils.Byf(fmt.Sprintf("Sending message to %s", GetDestination(info)))
+
+	if info.DryRun {
+		utils.Byf("Send message %q to %s",
+			strings.Join(append([]string{msg.Header}, msg.Sections...), "\n"), GetDestination(info))
+		for i := range msg.Replies {
+			utils.Byf("Reply %q in thread (broadcast: %t)", strings.Join(msg.Replies[i].Sections, "\n"),
+				msg.Replies[i].Broadcast)
+		}
+		if msg.File != nil {
+			utils.Byf("Upload file %s (%d bytes)", msg.File.Name, len(msg.File.Content))
+		}
+		return nil
+	}
+
+	if info.WebhookURL != "" {
+		if msg.File != nil {
+			return fmt.Errorf("files cannot be uploaded to slack incoming webhooks")
+		}
+		return sendWebhookMessage(info, msg)
 	}
 
-	utils.Byf(fmt.Sprintf("Sending message to channel %s", info.Channel))
+	// A file which cannot be uploaded does not prevent message from being sent
+	var uploadErr error
+	if msg.File != nil {
+		msg, uploadErr = withUploadedFile(info, channelID, msg)
+	}
 
-	channelID, err := getChannelID(info)
+	threadTS := ""
+	for _, blocks := range getBlocks(msg) {
+		var ts string
+		err := retryRateLimited(func() (err error) {
+			_, ts, err = api.PostMessage(channelID, slack.MsgOptionText(msg.Text, false), slack.MsgOptionBlocks(blocks...))
+			return err
+		})
+		if err != nil {
+			utils.Byf(fmt.Sprintf("Failed to send message. Error: %v", err))
+			return fmt.Errorf("failed to send message to %s: %w", GetDestination(info), err)
+		}
+		if threadTS == "" {
+			threadTS = ts
+		}
+	}
+
+	for i := range msg.Replies {
+		if err := sendReply(api, channelID, threadTS, &msg.Replies[i]); err != nil {
+			utils.Byf(fmt.Sprintf("Failed to send reply. Error: %v", err))
+			return fmt.Errorf("failed to send reply to %s: %w", GetDestination(info), err)
+		}
+	}
+
+	return uploadErr
+}
+
+// withUploadedFile uploads msg file to channel and returns a copy of msg with a section
+// linking to the uploaded file. On error, msg is returned as is.
+func withUploadedFile(info *SlackInfo, channelID string, msg *Message) (*Message, error) {
+	utils.Byf(fmt.Sprintf("Uploading file %s", msg.File.Name))
+	permalink, err := uploadFile(info, channelID, msg.File)
 	if err != nil {
-		utils.Byf(fmt.Sprintf("failed to get channel %s", info.Channel))
-		return
+		utils.Byf(fmt.Sprintf("Failed to upload file %s. Error: %v", msg.File.Name, err))
+		return msg, fmt.Errorf("failed to upload file %s to %s: %w", msg.File.Name, GetDestination(info), err)
 	}
 
-	if info.DryRun {
-		utils.Byf("Send message %q to channel %s", text, info.Channel)
-		return
+	linked := *msg
+	linked.Sections = append(append([]string{}, msg.Sections...),
+		fmt.Sprintf(":page_facing_up: <%s|%s>", permalink, Escape(msg.File.Title)))
+	return &linked, nil
+}
+
+// sendReply posts reply in the thread of message with timestamp threadTS
+func sendReply(api *slack.Client, channelID, threadTS string, reply *Reply) error {
+	for _, blocks := range getBlocks(&Message{Sections: reply.Sections}) {
+		options := []slack.MsgOption{slack.MsgOptionText(reply.Text, false), slack.MsgOptionBlocks(blocks...),
+			slack.MsgOptionTS(threadTS)}
+		if reply.Broadcast {
+			options = append(options, slack.MsgOptionBroadcast())
+		}
+		err := retryRateLimited(func() error {
+			_, _, err := api.PostMessage(channelID, options...)
+			return err
+		})
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	_, _, err = api.PostMessage(channelID, slack.MsgOptionText(text, false))
+// slackMaxRetries is the maximum number of times a post rate limited by Slack is retried
+const slackMaxRetries = 5
+
+// retryRateLimited invokes post and, as long as Slack rate limits it, invokes it again
+// after the time Slack asks to wait, up to slackMaxRetries times
+func retryRateLimited(post func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := post()
+		var rateLimited *slack.RateLimitedError
+		if err == nil || !errors.As(err, &rateLimited) || attempt >= slackMaxRetries {
+			return err
+		}
+		utils.Byf(fmt.Sprintf("Slack rate limit exceeded. Retrying in %s", rateLimited.RetryAfter))
+		time.Sleep(rateLimited.RetryAfter)
+	}
+}
+
+// sendWebhookMessage posts message, and then its replies, to info.WebhookURL
+func sendWebhookMessage(info *SlackInfo, msg *Message) error {
+	post := func(text string, blocks []slack.Block) error {
+		webhookMsg := &slack.WebhookMessage{Text: text, Blocks: &slack.Blocks{BlockSet: blocks}}
+		err := retryRateLimited(func() error {
+			return slack.PostWebhook(info.WebhookURL, webhookMsg)
+		})
+		if err != nil {
+			utils.Byf(fmt.Sprintf("Failed to send message. Error: %v", err))
+			return fmt.Errorf("failed to send message to slack incoming webhook: %w", err)
+		}
+		return nil
+	}
+
+	for _, blocks := range getBlocks(msg) {
+		if err := post(msg.Text, blocks); err != nil {
+			return err
+		}
+	}
+
+	for i := range msg.Replies {
+		for _, blocks := range getBlocks(&Message{Sections: msg.Replies[i].Sections}) {
+			if err := post(msg.Replies[i].Text, blocks); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// verifyWebhookURL verifies webhookURL is an absolute http(s) URL
+func verifyWebhookURL(webhookURL string) error {
+	u, err := url.Parse(webhookURL)
 	if err != nil {
-		utils.Byf(fmt.Sprintf("Failed to send message. Error: %v", err))
+		return fmt.Errorf("invalid slack webhook URL: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid slack webhook URL: scheme must be http or https and host must be set")
+	}
+	return nil
+}
+
+// getSlackClient returns a Slack API client. If set, info.APIURL is used as API URL.
+func getSlackClient(info *SlackInfo) *slack.Client {
+	return slack.New(info.AuthToken, slack.OptionAPIURL(getAPIURL(info)))
+}
+
+// GetDestination returns where messages are sent to (webhook, channel ID or channel name),
+// for logs and errors
+func GetDestination(info *SlackInfo) string {
+	switch {
+	case info.WebhookURL != "":
+		return "incoming webhook"
+	case info.ChannelID != "":
+		return fmt.Sprintf("channel %s", info.ChannelID)
+	default:
+		return fmt.Sprintf("channel %s", info.Channel)
 	}
 }
 
+// channelIDRegexp matches Slack channel IDs (public, private and direct message channels)
+var channelIDRegexp = regexp.MustCompile(`^[CGD][A-Z0-9]{8,}$`)
+
+// channelIDs caches resolved channel IDs, so channels are listed at most once per process
+var (
+	channelIDs   = make(map[string]string)
+	channelIDsMu sync.Mutex
+)
+
+// getChannelID returns the ID of the channel messages are sent to: info.ChannelID if set,
+// info.Channel if it is a channel ID, otherwise the ID of the channel named info.Channel
+// (a leading # is ignored). Both public and private channels the bot is a member of are
+// looked up. Resolved IDs are cached.
 func getChannelID(info *SlackInfo) (string, error) {
-	api := slack.New(info.AuthToken)
+	if info.ChannelID != "" {
+		return info.ChannelID, nil
+	}
+	if channelIDRegexp.MatchString(info.Channel) {
+		return info.Channel, nil
+	}
+
+	channelIDsMu.Lock()
+	defer channelIDsMu.Unlock()
+
+	key := fmt.Sprintf("%s|%s|%s", info.APIURL, info.AuthToken, info.Channel)
+	if id, ok := channelIDs[key]; ok {
+		return id, nil
+	}
+
+	id, err := findChannelID(info, strings.TrimPrefix(info.Channel, "#"))
+	if err != nil {
+		return "", err
+	}
+	channelIDs[key] = id
+	return id, nil
+}
+
+// findChannelID pages through public and private channels looking for the one named name
+func findChannelID(info *SlackInfo, name string) (string, error) {
+	api := getSlackClient(info)
+	cursor := ""
 	for {
-		cursor := ""
 		channels, nextCursor, err := api.GetConversations(&slack.GetConversationsParameters{
 			ExcludeArchived: true,
 			Cursor:          cursor,
-			Limit:           50,
+			Limit:           200,
+			Types:           []string{"public_channel", "private_channel"},
 		})
 		if err != nil {
 			return "", fmt.Errorf("failed to get channels. Err: %v", err)
 		}
 
 		for i := range channels {
-			if channels[i].Name == info.Channel {
+			if channels[i].Name == name {
 				return channels[i].ID, nil
 			}
 		}
 
 		cursor = nextCursor
 		if cursor == "" {
-			return "", fmt.Errorf("failed to find channel %s", info.Channel)
+			return "", fmt.Errorf("failed to find channel %s", name)
 		}
 	}
 }
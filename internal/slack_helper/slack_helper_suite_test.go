@@ -0,0 +1,13 @@
+package slack_helper_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSlackHelper(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SlackHelper Suite")
+}
@@ -0,0 +1,172 @@
+package slack_helper_test
+
+import (
+	"context"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/slack_helper"
+)
+
+var _ = Describe("Slack helper", func() {
+	var fake *fakeSlack
+	var server *httptest.Server
+	var msg *slack_helper.Message
+
+	BeforeEach(func() {
+		fake = newFakeSlack("general", "random", "e2e")
+		server = httptest.NewServer(fake)
+		fake.url = server.URL
+		msg = &slack_helper.Message{
+			Header:   "e2e run 3: 1 failed, 2 passed",
+			Sections: []string{":x: *list* failed in run 3"},
+			Text:     "e2e run 3: 1 failed, 2 passed",
+			Replies: []slack_helper.Reply{
+				{Sections: []string{"*Failure*"}, Text: "list failed", Broadcast: true},
+				{Sections: []string{"*Flaky tests*"}, Text: "Flaky tests"},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		slack_helper.ResetChannelIDs()
+	})
+
+	It("VerifyInfo uses channel ID without looking channel up", func() {
+		info := &slack_helper.SlackInfo{AuthToken: fake.token, ChannelID: "C999", APIURL: server.URL + "/api"}
+		Expect(slack_helper.VerifyInfo(context.TODO(), info)).To(Succeed())
+		Expect(fake.requests).To(Equal([]string{"auth.test"}))
+	})
+
+	It("VerifyInfo reports invalid tokens", func() {
+		info := &slack_helper.SlackInfo{AuthToken: "wrong", ChannelID: "C999", APIURL: server.URL + "/api/"}
+		Expect(slack_helper.VerifyInfo(context.TODO(), info)).To(MatchError(ContainSubstring("invalid_auth")))
+	})
+
+	It("VerifyInfo only verifies webhook URL", func() {
+		info := &slack_helper.SlackInfo{WebhookURL: server.URL + "/webhook"}
+		Expect(slack_helper.VerifyInfo(context.TODO(), info)).To(Succeed())
+		Expect(fake.requests).To(BeEmpty())
+
+		info.WebhookURL = "hooks.slack.com/services/T0/B0/XYZ"
+		Expect(slack_helper.VerifyInfo(context.TODO(), info)).To(MatchError(ContainSubstring("invalid slack webhook URL")))
+	})
+
+	It("SendSlackMessage posts message to channel ID and replies in its thread", func() {
+		info := &slack_helper.SlackInfo{AuthToken: fake.token, ChannelID: "C999", APIURL: server.URL + "/api/"}
+		Expect(slack_helper.SendSlackMessage(info, msg)).To(Succeed())
+
+		Expect(fake.requests).ToNot(ContainElement("conversations.list"))
+		Expect(fake.posted).To(HaveLen(3))
+		Expect(fake.posted[0].Channel).To(Equal("C999"))
+		Expect(fake.posted[0].Text).To(Equal(msg.Text))
+		Expect(fake.posted[0].Blocks).To(HaveLen(2))
+		Expect(fake.posted[0].ThreadTS).To(BeEmpty())
+		Expect(fake.posted[1].ThreadTS).To(Equal("1700000000.000001"))
+		Expect(fake.posted[1].ReplyBroadcast).To(BeTrue())
+		Expect(fake.posted[2].ThreadTS).To(Equal("1700000000.000001"))
+		Expect(fake.posted[2].ReplyBroadcast).To(BeFalse())
+	})
+
+	It("SendSlackMessage retries replies rate limited by slack", func() {
+		fake.rateLimited = map[int]bool{2: true}
+		info := &slack_helper.SlackInfo{AuthToken: fake.token, ChannelID: "C999", APIURL: server.URL + "/api/"}
+		Expect(slack_helper.SendSlackMessage(info, msg)).To(Succeed())
+
+		Expect(fake.postCalls).To(Equal(4))
+		Expect(fake.posted).To(HaveLen(3))
+		Expect(fake.posted[1].Text).To(Equal("list failed"))
+		Expect(fake.posted[1].ThreadTS).To(Equal("1700000000.000001"))
+		Expect(fake.posted[2].Text).To(Equal("Flaky tests"))
+	})
+
+	It("SendSlackMessage posts message and replies to webhook", func() {
+		info := &slack_helper.SlackInfo{WebhookURL: server.URL + "/webhook"}
+		Expect(slack_helper.SendSlackMessage(info, msg)).To(Succeed())
+
+		Expect(fake.requests).To(BeEmpty())
+		Expect(fake.webhook).To(HaveLen(3))
+		Expect(fake.webhook[0].Text).To(Equal(msg.Text))
+		Expect(fake.webhook[0].Blocks).To(HaveLen(2))
+		Expect(fake.webhook[1].Text).To(Equal("list failed"))
+		Expect(fake.webhook[2].Text).To(Equal("Flaky tests"))
+	})
+
+	It("SendSlackMessage does not post on dry run", func() {
+		info := &slack_helper.SlackInfo{WebhookURL: server.URL + "/webhook", DryRun: true}
+		Expect(slack_helper.SendSlackMessage(info, msg)).To(Succeed())
+		Expect(fake.webhook).To(BeEmpty())
+	})
+
+	It("resolves channels on any page, once per process", func() {
+		info := &slack_helper.SlackInfo{AuthToken: fake.token, Channel: "e2e", APIURL: server.URL + "/api/"}
+		Expect(slack_helper.VerifyInfo(context.TODO(), info)).To(Succeed())
+		Expect(slack_helper.SendSlackMessage(info, msg)).To(Succeed())
+
+		Expect(fake.requests).To(Equal([]string{"auth.test", "conversations.list", "conversations.list",
+			"chat.postMessage", "chat.postMessage", "chat.postMessage"}))
+		Expect(fake.posted[0].Channel).To(Equal("C002"))
+	})
+
+	It("resolves private channels and ignores leading #", func() {
+		fake.channels[2].IsPrivate = true
+		info := &slack_helper.SlackInfo{AuthToken: fake.token, Channel: "#e2e", APIURL: server.URL + "/api/"}
+		Expect(slack_helper.VerifyInfo(context.TODO(), info)).To(Succeed())
+		Expect(slack_helper.SendSlackMessage(info, msg)).To(Succeed())
+		Expect(fake.posted[0].Channel).To(Equal("C002"))
+	})
+
+	It("reports channels which cannot be found", func() {
+		info := &slack_helper.SlackInfo{AuthToken: fake.token, Channel: "qa", APIURL: server.URL + "/api/"}
+		Expect(slack_helper.VerifyInfo(context.TODO(), info)).To(MatchError("failed to find channel qa"))
+		Expect(fake.requests).To(HaveLen(3))
+	})
+
+	It("uses channel IDs passed as channel", func() {
+		info := &slack_helper.SlackInfo{AuthToken: fake.token, Channel: "C0123ABCD", APIURL: server.URL + "/api/"}
+		Expect(slack_helper.SendSlackMessage(info, msg)).To(Succeed())
+		Expect(fake.requests).ToNot(ContainElement("conversations.list"))
+		Expect(fake.posted[0].Channel).To(Equal("C0123ABCD"))
+	})
+
+	It("SendSlackMessage uploads file to channel and links it from message", func() {
+		msg.File = &slack_helper.File{Name: "failures-run-3.txt", Title: "Failure log of run 3", Content: []byte("stack")}
+		info := &slack_helper.SlackInfo{AuthToken: fake.token, ChannelID: "C999", APIURL: server.URL + "/api/"}
+		Expect(slack_helper.SendSlackMessage(info, msg)).To(Succeed())
+
+		Expect(fake.uploads).To(HaveKeyWithValue("F001", []byte("stack")))
+		Expect(fake.shared).To(HaveKeyWithValue("F001", "C999"))
+		Expect(fake.requests[:3]).To(Equal([]string{"files.getUploadURLExternal", "files.completeUploadExternal",
+			"chat.postMessage"}))
+		Expect(fake.posted[0].Blocks).To(HaveLen(3))
+		Expect(string(fake.posted[0].Blocks[2])).To(ContainSubstring(
+			":page_facing_up: \\u003chttps://e2e.slack.com/files/U0BOT/F001|Failure log of run 3\\u003e"))
+		Expect(msg.Sections).To(HaveLen(1))
+	})
+
+	It("SendSlackMessage sends message when file cannot be uploaded", func() {
+		fake.uploadError = "missing_scope"
+		msg.File = &slack_helper.File{Name: "failures-run-3.txt", Title: "Failure log of run 3", Content: []byte("stack")}
+		info := &slack_helper.SlackInfo{AuthToken: fake.token, ChannelID: "C999", APIURL: server.URL + "/api/"}
+		Expect(slack_helper.SendSlackMessage(info, msg)).To(MatchError(ContainSubstring("missing_scope")))
+		Expect(fake.posted).To(HaveLen(3))
+		Expect(fake.posted[0].Blocks).To(HaveLen(2))
+	})
+
+	It("SendSlackMessage does not upload files to webhooks", func() {
+		msg.File = &slack_helper.File{Name: "failures-run-3.txt", Content: []byte("stack")}
+		info := &slack_helper.SlackInfo{WebhookURL: server.URL + "/webhook"}
+		Expect(slack_helper.SendSlackMessage(info, msg)).ToNot(Succeed())
+		Expect(fake.webhook).To(BeEmpty())
+	})
+	It("GetDestination returns webhook, channel ID or channel name", func() {
+		Expect(slack_helper.GetDestination(&slack_helper.SlackInfo{WebhookURL: "http://hook", Channel: "e2e"})).
+			To(Equal("incoming webhook"))
+		Expect(slack_helper.GetDestination(&slack_helper.SlackInfo{ChannelID: "C01234567", Channel: "e2e"})).
+			To(Equal("channel C01234567"))
+		Expect(slack_helper.GetDestination(&slack_helper.SlackInfo{Channel: "e2e"})).To(Equal("channel e2e"))
+	})
+})
@@ -0,0 +1,127 @@
+package slack_helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// File is a text file uploaded, and linked from, along with a Message
+type File struct {
+	Name    string // file name, e.g. failures-run-12.txt
+	Title   string // file title
+	Content []byte // file content
+}
+
+// apiResponse is the common part of Slack Web API responses
+type apiResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// uploadURLResponse is the response of files.getUploadURLExternal
+type uploadURLResponse struct {
+	apiResponse
+	UploadURL string `json:"upload_url"`
+	FileID    string `json:"file_id"`
+}
+
+// completeUploadResponse is the response of files.completeUploadExternal
+type completeUploadResponse struct {
+	apiResponse
+	Files []struct {
+		ID        string `json:"id"`
+		Permalink string `json:"permalink"`
+	} `json:"files"`
+}
+
+// uploadFile uploads file and shares it in channel channelID using files upload v2 API:
+// - files.getUploadURLExternal returns the URL to upload file to;
+// - file content is posted to that URL;
+// - files.completeUploadExternal completes the upload and shares file.
+// Returns the file permalink.
+func uploadFile(info *SlackInfo, channelID string, file *File) (string, error) {
+	uploadURL := &uploadURLResponse{}
+	if err := callAPI(info, "files.getUploadURLExternal", url.Values{
+		"filename": {file.Name},
+		"length":   {strconv.Itoa(len(file.Content))},
+	}, uploadURL); err != nil {
+		return "", err
+	}
+
+	resp, err := http.Post(uploadURL.UploadURL, "application/octet-stream", bytes.NewReader(file.Content))
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file %s: %w", file.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to upload file %s: status %s", file.Name, resp.Status)
+	}
+
+	files, err := json.Marshal([]map[string]string{{"id": uploadURL.FileID, "title": file.Title}})
+	if err != nil {
+		return "", err
+	}
+	completed := &completeUploadResponse{}
+	if err := callAPI(info, "files.completeUploadExternal", url.Values{
+		"files":      {string(files)},
+		"channel_id": {channelID},
+	}, completed); err != nil {
+		return "", err
+	}
+	if len(completed.Files) == 0 {
+		return "", fmt.Errorf("files.completeUploadExternal returned no file")
+	}
+
+	return completed.Files[0].Permalink, nil
+}
+
+// callAPI calls Slack Web API method with form values and decodes the response in result,
+// which must embed apiResponse. Returns an error if response is not ok.
+func callAPI(info *SlackInfo, method string, values url.Values, result interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, getAPIURL(info)+method, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+info.AuthToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", method, err)
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("%s failed: status %s: %w", method, resp.Status, err)
+	}
+
+	response := &apiResponse{}
+	_ = json.Unmarshal(body, response)
+	if !response.OK {
+		return fmt.Errorf("%s failed: %s", method, response.Error)
+	}
+	return nil
+}
+
+// getAPIURL returns the slack API URL, ending with /
+func getAPIURL(info *SlackInfo) string {
+	if info.APIURL == "" {
+		return slack.APIURL
+	}
+	if !strings.HasSuffix(info.APIURL, "/") {
+		return info.APIURL + "/"
+	}
+	return info.APIURL
+}
@@ -1,20 +1,133 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
 
 	. "github.com/onsi/ginkgo/v2" // nolint: golint,stylecheck // ginkgo pattern
 )
 
-var logEnabled = false
+var (
+	logEnabled = false
+	logWriter  io.Writer
+)
 
 func Init(doLogs bool) {
 	logEnabled = doLogs
 }
 
+// SetWriter sets the writer logs are written to. When nil (default), logs are
+// reported using ginkgo By, which is only allowed while a test-suite is running.
+func SetWriter(w io.Writer) {
+	logWriter = w
+}
+
 // Byf is a simple wrapper around By.
 func Byf(format string, a ...interface{}) {
 	if logEnabled {
+		if logWriter != nil {
+			fmt.Fprintln(logWriter, fmt.Sprintf(format, a...))
+			return
+		}
 		By(fmt.Sprintf(format, a...))
 	}
 }
+
+// TruncateHead returns the last maxSize bytes of s, prefixed by a marker, if s is
+// longer than maxSize. The end of an output is usually what matters for a failure.
+func TruncateHead(s string, maxSize int) string {
+	const marker = "...[truncated]\n"
+	if maxSize <= 0 || len(s) <= maxSize {
+		return s
+	}
+
+	start := len(s) - maxSize
+	// Do not split a multi-byte character
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return marker + s[start:]
+}
+
+// TruncateTail returns the first maxSize bytes of s, followed by a marker, if s is
+// longer than maxSize. The beginning of a stack trace is usually what matters for a failure.
+func TruncateTail(s string, maxSize int) string {
+	const marker = "\n...[truncated]"
+	if maxSize <= 0 || len(s) <= maxSize {
+		return s
+	}
+
+	end := maxSize
+	// Do not split a multi-byte character
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end] + marker
+}
+
+// AggregateErrors returns a single error combining all errs.
+// Returns nil if errs is empty, errs[0] if errs contains a single error
+// and a *MultiError otherwise.
+func AggregateErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+
+	return &MultiError{Errors: errs}
+}
+
+// MultiError is an error combining several errors. errors.Is and errors.As
+// match any of the combined errors.
+type MultiError struct {
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i := range e.Errors {
+		msgs[i] = e.Errors[i].Error()
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the combined errors
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+// Is reports whether any of the combined errors matches target
+func (e *MultiError) Is(target error) bool {
+	return IsAny(e.Errors, target)
+}
+
+// As finds the first of the combined errors matching target
+func (e *MultiError) As(target interface{}) bool {
+	return AsAny(e.Errors, target)
+}
+
+// IsAny reports whether any of errs matches target (see errors.Is)
+func IsAny(errs []error, target error) bool {
+	for i := range errs {
+		if errors.Is(errs[i], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// AsAny finds the first of errs matching target and, if found, sets target
+// to that error (see errors.As)
+func AsAny(errs []error, target interface{}) bool {
+	for i := range errs {
+		if errors.As(errs[i], target) {
+			return true
+		}
+	}
+	return false
+}
@@ -57,17 +57,17 @@ func getRoom(c *webexteams.Client, roomName string) (*webexteams.Room, error) {
 
 // SendWebexMessage sends webex message to specified room.
 // text is a markdown message
-func SendWebexMessage(info *WebexInfo, text string) {
+func SendWebexMessage(info *WebexInfo, text string) error {
 	utils.Byf(fmt.Sprintf("Get room %s", info.Room))
 	c := getWebexClient(info.AuthToken)
 	room, err := getRoom(c, info.Room)
 	if err != nil {
 		utils.Byf(fmt.Sprintf("failed to get room %s. Error: %v", info.Room, err))
-		return
+		return fmt.Errorf("failed to get room %s: %w", info.Room, err)
 	}
 	if room == nil {
 		utils.Byf(fmt.Sprintf("failed to get room %s.", info.Room))
-		return
+		return fmt.Errorf("failed to get room %s", info.Room)
 	}
 
 	utils.Byf(fmt.Sprintf("Sending message to room %s", info.Room))
@@ -78,15 +78,18 @@ func SendWebexMessage(info *WebexInfo, text string) {
 
 	if info.DryRun {
 		utils.Byf("Send message %q to room %s", message.Markdown, room.Title)
-		return
+		return nil
 	}
 
 	_, resp, err := c.Messages.CreateMessage(message)
 	if err != nil {
 		if resp != nil {
 			utils.Byf(fmt.Sprintf("Failed to send message. Error: %v. Response: %s", err, string(resp.Body())))
-			return
+		} else {
+			utils.Byf(fmt.Sprintf("Failed to send message. Error: %v", err))
 		}
-		utils.Byf(fmt.Sprintf("Failed to send message. Error: %v", err))
+		return fmt.Errorf("failed to send message to room %s: %w", info.Room, err)
 	}
+
+	return nil
 }
@@ -0,0 +1,564 @@
+package process_result
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Configuration file keys
+const (
+	ElasticURLKey           = "ELASTIC_URL"
+	ElasticIndexKey         = "ELASTIC_INDEX"
+	ElasticBulkSizeKey      = "ELASTIC_BULK_SIZE"
+	ElasticFlushIntervalKey = "ELASTIC_FLUSH_INTERVAL"
+	ElasticCreateIndexKey   = "ELASTIC_CREATE_INDEX"
+	ElasticMaxOutputSizeKey = "ELASTIC_MAX_OUTPUT_SIZE"
+	ElasticSummaryIndexKey  = "ELASTIC_SUMMARY_INDEX"
+
+	WebexAuthTokenKey = "WEBEX_AUTH_TOKEN"
+	WebexRoomKey      = "WEBEX_ROOM"
+
+	SlackAuthTokenKey            = "SLACK_AUTH_TOKEN"
+	SlackChannelKey              = "SLACK_CHANNEL"
+	SlackChannelIDKey            = "SLACK_CHANNEL_ID"
+	SlackWebhookURLKey           = "SLACK_WEBHOOK_URL"
+	SlackAPIURLKey               = "SLACK_API_URL"
+	SlackThreadedKey             = "SLACK_THREADED"
+	SlackBroadcastNewFailuresKey = "SLACK_BROADCAST_NEW_FAILURES"
+	SlackUploadFailureLogKey     = "SLACK_UPLOAD_FAILURE_LOG"
+
+	JiraBaseURLKey   = "JIRA_BASE_URL"
+	JiraProjectKey   = "JIRA_PROJECT"
+	JiraBoardKey     = "JIRA_BOARD"
+	JiraComponentKey = "JIRA_COMPONENT"
+	JiraUsernameKey  = "JIRA_USERNAME"
+	JiraPasswordKey  = "JIRA_PASSWORD"
+
+	JiraAPITokenKey            = "JIRA_API_TOKEN"
+	JiraPersonalAccessTokenKey = "JIRA_PERSONAL_ACCESS_TOKEN"
+	JiraCloudKey               = "JIRA_CLOUD"
+
+	JiraAttachOutputKey      = "JIRA_ATTACH_OUTPUT"
+	JiraArtifactEntriesKey   = "JIRA_ARTIFACT_ENTRIES" // comma separated list
+	JiraMaxAttachmentSizeKey = "JIRA_MAX_ATTACHMENT_SIZE"
+
+	JiraSprintPolicyKey = "JIRA_SPRINT_POLICY" // none, active, named or new-issues
+	JiraSprintNameKey   = "JIRA_SPRINT_NAME"
+
+	JiraResolveAfterPassesKey = "JIRA_RESOLVE_AFTER_PASSES"
+	JiraResolvedStatusKey     = "JIRA_RESOLVED_STATUS"
+	JiraIssueTypeKey          = "JIRA_ISSUE_TYPE"
+	JiraPriorityKey           = "JIRA_PRIORITY"
+	JiraSerialPriorityKey     = "JIRA_SERIAL_PRIORITY"
+	JiraPriorityByLabelKey    = "JIRA_PRIORITY_BY_LABEL" // comma separated list of label=priority
+	JiraLabelsKey             = "JIRA_LABELS"            // comma separated list
+	JiraDoneStatusesKey       = "JIRA_DONE_STATUSES"     // comma separated list
+
+	// JiraCustomFieldKeyPrefix is the prefix of keys setting a custom field, e.g.
+	// JIRA_CUSTOM_FIELD_customfield_10010: "Found in run {{ .RunID }}"
+	JiraCustomFieldKeyPrefix = "JIRA_CUSTOM_FIELD_"
+
+	RegressionStateFileKey          = "REGRESSION_STATE_FILE"
+	RegressionNotifyOnlyOnChangeKey = "REGRESSION_NOTIFY_ONLY_ON_CHANGE"
+
+	OwnershipFileKey = "OWNERSHIP_FILE"
+)
+
+// configKeys contains, per section, all supported keys. Value indicates
+// whether the key is required.
+var configKeys = map[string]map[string]bool{
+	"elastic": {
+		ElasticURLKey:           true,
+		ElasticIndexKey:         true,
+		ElasticBulkSizeKey:      false,
+		ElasticFlushIntervalKey: false,
+		ElasticCreateIndexKey:   false,
+		ElasticMaxOutputSizeKey: false,
+		ElasticSummaryIndexKey:  false,
+	},
+	"webex": {
+		WebexAuthTokenKey: true,
+		WebexRoomKey:      true,
+	},
+	"slack": {
+		SlackAuthTokenKey:  false, // see configRequiredUnless
+		SlackChannelKey:    false, // see configRequiredUnless
+		SlackChannelIDKey:  false,
+		SlackWebhookURLKey: false,
+		SlackAPIURLKey:     false,
+
+		SlackThreadedKey:             false,
+		SlackBroadcastNewFailuresKey: false,
+		SlackUploadFailureLogKey:     false,
+	},
+	"jira": {
+		JiraBaseURLKey:   true,
+		JiraProjectKey:   true,
+		JiraBoardKey:     false,
+		JiraComponentKey: false,
+		JiraUsernameKey:  false, // see configRequiredUnless
+		JiraPasswordKey:  false,
+
+		JiraAPITokenKey:            false,
+		JiraPersonalAccessTokenKey: false,
+		JiraCloudKey:               false,
+
+		JiraAttachOutputKey:      false,
+		JiraArtifactEntriesKey:   false,
+		JiraMaxAttachmentSizeKey: false,
+
+		JiraSprintPolicyKey: false,
+		JiraSprintNameKey:   false,
+
+		JiraResolveAfterPassesKey: false,
+		JiraResolvedStatusKey:     false,
+		JiraIssueTypeKey:          false,
+		JiraPriorityKey:           false,
+		JiraSerialPriorityKey:     false,
+		JiraPriorityByLabelKey:    false,
+		JiraLabelsKey:             false,
+		JiraDoneStatusesKey:       false,
+	},
+	"regression": {
+		RegressionStateFileKey:          false,
+		RegressionNotifyOnlyOnChangeKey: false,
+	},
+	"ownership": {
+		OwnershipFileKey: true,
+	},
+}
+
+// configRequiredUnless contains optional keys which are required unless any of
+// the keys they map to is set
+var configRequiredUnless = map[string][]string{
+	JiraUsernameKey: {JiraPersonalAccessTokenKey},
+
+	SlackAuthTokenKey: {SlackWebhookURLKey},
+	SlackChannelKey:   {SlackChannelIDKey, SlackWebhookURLKey},
+}
+
+// configKeyPrefixes contains, per section, the prefixes of supported optional keys
+var configKeyPrefixes = map[string][]string{
+	"jira": {JiraCustomFieldKeyPrefix},
+}
+
+// configParsers contains, for keys whose value is not a plain string, the
+// function validating the value
+var configParsers = map[string]func(string) error{
+	ElasticBulkSizeKey:      parsePositiveInt,
+	ElasticFlushIntervalKey: parseDuration,
+	ElasticCreateIndexKey:   parseBool,
+	ElasticMaxOutputSizeKey: parsePositiveInt,
+
+	SlackThreadedKey:             parseBool,
+	SlackBroadcastNewFailuresKey: parseBool,
+	SlackUploadFailureLogKey:     parseBool,
+
+	JiraCloudKey:              parseBool,
+	JiraAttachOutputKey:       parseBool,
+	JiraMaxAttachmentSizeKey:  parsePositiveInt,
+	JiraSprintPolicyKey:       parseSprintPolicy,
+	JiraResolveAfterPassesKey: parsePositiveInt,
+	JiraPriorityByLabelKey:    parseKeyValueList,
+
+	RegressionNotifyOnlyOnChangeKey: parseBool,
+}
+
+// envVarRegexp matches ${ENV} references
+var envVarRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Config is the content of a configuration file. Both YAML and JSON are supported.
+// Each section is optional. A section that is present enables the corresponding sink.
+// Any value can reference environment variables with ${ENV}.
+//
+//	jira:
+//	    JIRA_BASE_URL: "your jira base url"
+//	    JIRA_PROJECT: "your jira project"
+//	    JIRA_BOARD: "your jira board"
+//	    JIRA_USERNAME: "your jira username"
+//	    JIRA_PASSWORD: "${JIRA_PASSWORD}"
+//
+//	slack:
+//	    SLACK_AUTH_TOKEN: "${SLACK_AUTH_TOKEN}"
+//	    SLACK_CHANNEL: "your slack channel name"
+type Config struct {
+	// Jira contains jira configuration
+	Jira map[string]string `yaml:"jira,omitempty" json:"jira,omitempty"`
+
+	// Webex contains webex configuration
+	Webex map[string]string `yaml:"webex,omitempty" json:"webex,omitempty"`
+
+	// Slack contains slack configuration
+	Slack map[string]string `yaml:"slack,omitempty" json:"slack,omitempty"`
+
+	// Elastic contains elastic configuration
+	Elastic map[string]string `yaml:"elastic,omitempty" json:"elastic,omitempty"`
+
+	// Regression contains regression detection configuration. All keys are optional,
+	// use an empty section ({}) to enable regression detection with defaults.
+	Regression map[string]string `yaml:"regression,omitempty" json:"regression,omitempty"`
+
+	// Ownership contains the path of the file mapping tests to owners (see WithOwnershipFile)
+	Ownership map[string]string `yaml:"ownership,omitempty" json:"ownership,omitempty"`
+}
+
+// LoadConfig reads a configuration, expands environment variables and validates it.
+// Validation errors point to the offending key, for instance "slack.SLACK_CHANNEL".
+func LoadConfig(r io.Reader) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	sections := c.sections()
+	for _, name := range sortedKeys(sections) {
+		if err := validateSection(name, sections[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// WithConfigFile loads the configuration file at path and applies it.
+// Setters following this one override what is defined in the file.
+// Any error loading the file is returned by Register (or ProcessReport).
+func WithConfigFile(path string) Option {
+	return func(args *Options) {
+		f, err := os.Open(path)
+		if err != nil {
+			args.err = fmt.Errorf("failed to open config file %s: %w", path, err)
+			return
+		}
+		defer f.Close()
+
+		c, err := LoadConfig(f)
+		if err != nil {
+			args.err = fmt.Errorf("config file %s: %w", path, err)
+			return
+		}
+
+		for _, setter := range c.Options() {
+			setter(args)
+		}
+	}
+}
+
+// Options returns the setters corresponding to the configuration.
+func (c *Config) Options() []Option {
+	setters := make([]Option, 0)
+
+	if c.Elastic != nil {
+		// Values were already validated by LoadConfig
+		bulkSize, _ := strconv.Atoi(c.Elastic[ElasticBulkSizeKey])
+		flushInterval, _ := time.ParseDuration(c.Elastic[ElasticFlushIntervalKey])
+		createIndex, _ := strconv.ParseBool(c.Elastic[ElasticCreateIndexKey])
+		maxOutputSize, _ := strconv.Atoi(c.Elastic[ElasticMaxOutputSizeKey])
+		setters = append(setters, WithElastic(ElasticInfo{
+			URL:           c.Elastic[ElasticURLKey],
+			Index:         c.Elastic[ElasticIndexKey],
+			BulkSize:      bulkSize,
+			FlushInterval: flushInterval,
+			CreateIndex:   createIndex,
+			MaxOutputSize: maxOutputSize,
+			SummaryIndex:  c.Elastic[ElasticSummaryIndexKey],
+		}))
+	}
+
+	if c.Jira != nil {
+		resolveAfterPasses, _ := strconv.Atoi(c.Jira[JiraResolveAfterPassesKey])
+		cloud, _ := strconv.ParseBool(c.Jira[JiraCloudKey])
+		attachOutput, _ := strconv.ParseBool(c.Jira[JiraAttachOutputKey])
+		maxAttachmentSize, _ := strconv.Atoi(c.Jira[JiraMaxAttachmentSizeKey])
+		setters = append(setters, WithJira(JiraInfo{
+			BaseURL:   c.Jira[JiraBaseURLKey],
+			Project:   c.Jira[JiraProjectKey],
+			Board:     c.Jira[JiraBoardKey],
+			Component: c.Jira[JiraComponentKey],
+			Username:  c.Jira[JiraUsernameKey],
+			Password:  c.Jira[JiraPasswordKey],
+
+			APIToken:            c.Jira[JiraAPITokenKey],
+			PersonalAccessToken: c.Jira[JiraPersonalAccessTokenKey],
+			Cloud:               cloud,
+
+			AttachOutput:      attachOutput,
+			ArtifactEntries:   getList(c.Jira[JiraArtifactEntriesKey]),
+			MaxAttachmentSize: maxAttachmentSize,
+
+			SprintPolicy: JiraSprintPolicy(c.Jira[JiraSprintPolicyKey]),
+			SprintName:   c.Jira[JiraSprintNameKey],
+
+			ResolveAfterPasses: resolveAfterPasses,
+			ResolvedStatus:     c.Jira[JiraResolvedStatusKey],
+
+			IssueType:       c.Jira[JiraIssueTypeKey],
+			Priority:        c.Jira[JiraPriorityKey],
+			SerialPriority:  c.Jira[JiraSerialPriorityKey],
+			PriorityByLabel: getKeyValueList(c.Jira[JiraPriorityByLabelKey]),
+			Labels:          getList(c.Jira[JiraLabelsKey]),
+			CustomFields:    getWithPrefix(c.Jira, JiraCustomFieldKeyPrefix),
+			DoneStatuses:    getList(c.Jira[JiraDoneStatusesKey]),
+		}))
+	}
+
+	if c.Webex != nil {
+		setters = append(setters, WithWebex(WebexInfo{
+			AuthToken: c.Webex[WebexAuthTokenKey],
+			Room:      c.Webex[WebexRoomKey],
+		}))
+	}
+
+	if c.Slack != nil {
+		threaded, _ := strconv.ParseBool(c.Slack[SlackThreadedKey])
+		broadcastNewFailures, _ := strconv.ParseBool(c.Slack[SlackBroadcastNewFailuresKey])
+		uploadFailureLog, _ := strconv.ParseBool(c.Slack[SlackUploadFailureLogKey])
+		setters = append(setters, WithSlack(SlackInfo{
+			AuthToken:            c.Slack[SlackAuthTokenKey],
+			Channel:              c.Slack[SlackChannelKey],
+			ChannelID:            c.Slack[SlackChannelIDKey],
+			WebhookURL:           c.Slack[SlackWebhookURLKey],
+			APIURL:               c.Slack[SlackAPIURLKey],
+			Threaded:             threaded,
+			BroadcastNewFailures: broadcastNewFailures,
+			UploadFailureLog:     uploadFailureLog,
+		}))
+	}
+
+	if c.Regression != nil {
+		notifyOnlyOnChange, _ := strconv.ParseBool(c.Regression[RegressionNotifyOnlyOnChangeKey])
+		setters = append(setters, WithRegressionDetection(RegressionInfo{
+			StateFile:          c.Regression[RegressionStateFileKey],
+			NotifyOnlyOnChange: notifyOnlyOnChange,
+		}))
+	}
+
+	if c.Ownership != nil {
+		setters = append(setters, WithOwnershipFile(c.Ownership[OwnershipFileKey]))
+	}
+
+	return setters
+}
+
+// sections returns all sections present in the configuration
+func (c *Config) sections() map[string]map[string]string {
+	sections := make(map[string]map[string]string)
+	if c.Elastic != nil {
+		sections["elastic"] = c.Elastic
+	}
+	if c.Jira != nil {
+		sections["jira"] = c.Jira
+	}
+	if c.Webex != nil {
+		sections["webex"] = c.Webex
+	}
+	if c.Slack != nil {
+		sections["slack"] = c.Slack
+	}
+	if c.Regression != nil {
+		sections["regression"] = c.Regression
+	}
+	if c.Ownership != nil {
+		sections["ownership"] = c.Ownership
+	}
+	return sections
+}
+
+// validateSection expands environment variables in place and verifies
+// all keys are supported and all required keys are set.
+func validateSection(name string, values map[string]string) error {
+	supported := configKeys[name]
+
+	for _, key := range sortedKeys(values) {
+		if _, ok := supported[key]; !ok && !hasSupportedPrefix(name, key) {
+			return fmt.Errorf("%s.%s: unknown key", name, key)
+		}
+
+		value, err := expandEnv(values[key])
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", name, key, err)
+		}
+		values[key] = value
+
+		if parse, ok := configParsers[key]; ok && value != "" {
+			if err := parse(value); err != nil {
+				return fmt.Errorf("%s.%s: %w", name, key, err)
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(supported) {
+		if supported[key] && values[key] == "" {
+			return fmt.Errorf("%s.%s: required key is missing or empty", name, key)
+		}
+		if alternatives, ok := configRequiredUnless[key]; ok && values[key] == "" && !anySet(values, alternatives) {
+			return fmt.Errorf("%s.%s: required key is missing or empty (unless %s is set)", name, key,
+				strings.Join(alternatives, " or "))
+		}
+	}
+
+	return nil
+}
+
+// anySet returns true if any of keys is set in values
+func anySet(values map[string]string, keys []string) bool {
+	for _, key := range keys {
+		if values[key] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// expandEnv replaces any ${ENV} with the value of the environment variable.
+// Returns an error if any referenced environment variable is not set.
+func expandEnv(value string) (string, error) {
+	var err error
+	expanded := envVarRegexp.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envVarRegexp.FindStringSubmatch(ref)[1]
+		envValue, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+		return envValue
+	})
+
+	return expanded, err
+}
+
+// parsePositiveInt verifies value is a positive integer
+func parsePositiveInt(value string) error {
+	v, err := strconv.Atoi(value)
+	if err != nil || v <= 0 {
+		return fmt.Errorf("%q is not a positive integer", value)
+	}
+	return nil
+}
+
+// parseDuration verifies value is a duration (for instance 10s)
+func parseDuration(value string) error {
+	if _, err := time.ParseDuration(value); err != nil {
+		return fmt.Errorf("%q is not a valid duration", value)
+	}
+	return nil
+}
+
+// parseKeyValueList verifies value is a comma separated list of key=value
+func parseKeyValueList(value string) error {
+	for _, item := range getList(value) {
+		if k, v, ok := cutString(item, "="); !ok || k == "" || v == "" {
+			return fmt.Errorf("%q is not a key=value pair", item)
+		}
+	}
+	return nil
+}
+
+// parseSprintPolicy verifies value is a supported JiraSprintPolicy
+func parseSprintPolicy(value string) error {
+	switch JiraSprintPolicy(value) {
+	case JiraSprintPolicyNone, JiraSprintPolicyActive, JiraSprintPolicyNamed, JiraSprintPolicyNewIssues:
+		return nil
+	}
+	return fmt.Errorf("%q is not a valid sprint policy (none, active, named or new-issues)", value)
+}
+
+// parseBool verifies value is a boolean
+func parseBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("%q is not a valid boolean", value)
+	}
+	return nil
+}
+
+// sortedKeys returns map keys in sorted order so validation errors are deterministic
+func sortedKeys(m interface{}) []string {
+	keys := make([]string, 0)
+	switch v := m.(type) {
+	case map[string]string:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]bool:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]map[string]string:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hasSupportedPrefix returns true if key, in section, starts with a supported prefix
+func hasSupportedPrefix(section, key string) bool {
+	for _, prefix := range configKeyPrefixes[section] {
+		if strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// getList returns the items of a comma separated list. Items are trimmed, empty ones skipped.
+func getList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getKeyValueList returns the map corresponding to a comma separated list of key=value
+func getKeyValueList(value string) map[string]string {
+	items := getList(value)
+	if len(items) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(items))
+	for _, item := range items {
+		if k, v, ok := cutString(item, "="); ok {
+			result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return result
+}
+
+// getWithPrefix returns, with prefix removed, all keys starting with prefix
+func getWithPrefix(values map[string]string, prefix string) map[string]string {
+	var result map[string]string
+	for key, value := range values {
+		if strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			if result == nil {
+				result = make(map[string]string)
+			}
+			result[strings.TrimPrefix(key, prefix)] = value
+		}
+	}
+	return result
+}
+
+// cutString slices s around the first instance of sep
+func cutString(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
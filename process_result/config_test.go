@@ -0,0 +1,379 @@
+package process_result_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/process_result"
+)
+
+var _ = Describe("Config", func() {
+	It("LoadConfig loads a YAML configuration and expands environment variables", func() {
+		GinkgoT().Setenv("UT_SLACK_TOKEN", "xoxb-1234")
+		GinkgoT().Setenv("UT_JIRA_PASSWORD", "secret")
+
+		config, err := process_result.LoadConfig(strings.NewReader(`
+slack:
+    SLACK_AUTH_TOKEN: "${UT_SLACK_TOKEN}"
+    SLACK_CHANNEL: "qa testing"
+jira:
+    JIRA_BASE_URL: "https://jira.org"
+    JIRA_PROJECT: "my project"
+    JIRA_BOARD: "p1 board"
+    JIRA_USERNAME: "username"
+    JIRA_PASSWORD: "pre-${UT_JIRA_PASSWORD}-$post"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Webex).To(BeNil())
+		Expect(config.Elastic).To(BeNil())
+
+		c := &process_result.Options{}
+		for _, setter := range config.Options() {
+			setter(c)
+		}
+		Expect(c.SlackInfo).ToNot(BeNil())
+		Expect(*c.SlackInfo).To(Equal(process_result.SlackInfo{AuthToken: "xoxb-1234", Channel: "qa testing"}))
+		Expect(c.JiraInfo).ToNot(BeNil())
+		Expect(c.JiraInfo.Password).To(Equal("pre-secret-$post"))
+		Expect(c.ElasticInfo).To(BeNil())
+		Expect(c.WebexInfo).To(BeNil())
+	})
+
+	It("LoadConfig loads a JSON configuration", func() {
+		config, err := process_result.LoadConfig(strings.NewReader(
+			`{"elastic": {"ELASTIC_URL": "https://elastic.org", "ELASTIC_INDEX": "cs_e2e"}}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Elastic).To(HaveKeyWithValue(process_result.ElasticIndexKey, "cs_e2e"))
+	})
+
+	It("LoadConfig parses elastic bulk settings", func() {
+		config, err := process_result.LoadConfig(strings.NewReader(`
+elastic:
+    ELASTIC_URL: "https://elastic.org"
+    ELASTIC_INDEX: "cs_e2e"
+    ELASTIC_BULK_SIZE: "200"
+    ELASTIC_FLUSH_INTERVAL: "10s"
+    ELASTIC_CREATE_INDEX: "true"
+    ELASTIC_MAX_OUTPUT_SIZE: "4096"
+    ELASTIC_SUMMARY_INDEX: "cs_e2e_runs"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		c := &process_result.Options{}
+		for _, setter := range config.Options() {
+			setter(c)
+		}
+		Expect(c.ElasticInfo.BulkSize).To(Equal(200))
+		Expect(c.ElasticInfo.FlushInterval).To(Equal(10 * time.Second))
+		Expect(c.ElasticInfo.CreateIndex).To(BeTrue())
+		Expect(c.ElasticInfo.MaxOutputSize).To(Equal(4096))
+		Expect(c.ElasticInfo.SummaryIndex).To(Equal("cs_e2e_runs"))
+	})
+
+	It("LoadConfig parses jira auto-resolve settings", func() {
+		config, err := process_result.LoadConfig(strings.NewReader(`
+jira:
+    JIRA_BASE_URL: "https://jira.org"
+    JIRA_PROJECT: "my project"
+    JIRA_BOARD: "p1 board"
+    JIRA_USERNAME: "username"
+    JIRA_RESOLVE_AFTER_PASSES: "3"
+    JIRA_RESOLVED_STATUS: "Done"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		c := &process_result.Options{}
+		for _, setter := range config.Options() {
+			setter(c)
+		}
+		Expect(c.JiraInfo.ResolveAfterPasses).To(Equal(3))
+		Expect(c.JiraInfo.ResolvedStatus).To(Equal("Done"))
+	})
+
+	It("LoadConfig parses jira issue fields", func() {
+		config, err := process_result.LoadConfig(strings.NewReader(`
+jira:
+    JIRA_BASE_URL: "https://jira.org"
+    JIRA_PROJECT: "my project"
+    JIRA_BOARD: "p1 board"
+    JIRA_USERNAME: "username"
+    JIRA_ISSUE_TYPE: "Defect"
+    JIRA_PRIORITY: "High"
+    JIRA_SERIAL_PRIORITY: "Critical"
+    JIRA_PRIORITY_BY_LABEL: "smoke=Critical, slow=Low"
+    JIRA_LABELS: "e2e, nightly"
+    JIRA_DONE_STATUSES: "Done,Won't Fix"
+    JIRA_CUSTOM_FIELD_customfield_10010: "Found in run {{ .RunID }}"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		c := &process_result.Options{}
+		for _, setter := range config.Options() {
+			setter(c)
+		}
+		Expect(c.JiraInfo.IssueType).To(Equal("Defect"))
+		Expect(c.JiraInfo.Priority).To(Equal("High"))
+		Expect(c.JiraInfo.SerialPriority).To(Equal("Critical"))
+		Expect(c.JiraInfo.PriorityByLabel).To(Equal(map[string]string{"smoke": "Critical", "slow": "Low"}))
+		Expect(c.JiraInfo.Labels).To(Equal([]string{"e2e", "nightly"}))
+		Expect(c.JiraInfo.DoneStatuses).To(Equal([]string{"Done", "Won't Fix"}))
+		Expect(c.JiraInfo.CustomFields).To(Equal(map[string]string{"customfield_10010": "Found in run {{ .RunID }}"}))
+	})
+
+	It("LoadConfig parses jira Cloud settings", func() {
+		config, err := process_result.LoadConfig(strings.NewReader(`
+jira:
+    JIRA_BASE_URL: "https://org.atlassian.net"
+    JIRA_PROJECT: "my project"
+    JIRA_BOARD: "p1 board"
+    JIRA_USERNAME: "e2e@org.com"
+    JIRA_API_TOKEN: "token"
+    JIRA_CLOUD: "true"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		c := &process_result.Options{}
+		for _, setter := range config.Options() {
+			setter(c)
+		}
+		Expect(c.JiraInfo.APIToken).To(Equal("token"))
+		Expect(c.JiraInfo.Cloud).To(BeTrue())
+	})
+
+	It("LoadConfig parses jira attachment settings", func() {
+		config, err := process_result.LoadConfig(strings.NewReader(`
+jira:
+    JIRA_BASE_URL: "https://jira.org"
+    JIRA_PROJECT: "my project"
+    JIRA_BOARD: "p1 board"
+    JIRA_USERNAME: "username"
+    JIRA_ATTACH_OUTPUT: "true"
+    JIRA_ARTIFACT_ENTRIES: "cluster-dump, screenshot"
+    JIRA_MAX_ATTACHMENT_SIZE: "2048"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		c := &process_result.Options{}
+		for _, setter := range config.Options() {
+			setter(c)
+		}
+		Expect(c.JiraInfo.AttachOutput).To(BeTrue())
+		Expect(c.JiraInfo.ArtifactEntries).To(Equal([]string{"cluster-dump", "screenshot"}))
+		Expect(c.JiraInfo.MaxAttachmentSize).To(Equal(2048))
+	})
+
+	It("LoadConfig parses jira sprint settings and does not require a board", func() {
+		config, err := process_result.LoadConfig(strings.NewReader(`
+jira:
+    JIRA_BASE_URL: "https://jira.org"
+    JIRA_PROJECT: "my project"
+    JIRA_USERNAME: "username"
+    JIRA_SPRINT_POLICY: "named"
+    JIRA_SPRINT_NAME: "Hardening"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		c := &process_result.Options{}
+		for _, setter := range config.Options() {
+			setter(c)
+		}
+		Expect(c.JiraInfo.Board).To(BeEmpty())
+		Expect(c.JiraInfo.SprintPolicy).To(Equal(process_result.JiraSprintPolicyNamed))
+		Expect(c.JiraInfo.SprintName).To(Equal("Hardening"))
+	})
+
+	It("LoadConfig reports invalid sprint policy", func() {
+		_, err := process_result.LoadConfig(strings.NewReader(`
+jira:
+    JIRA_BASE_URL: "https://jira.org"
+    JIRA_PROJECT: "my project"
+    JIRA_USERNAME: "username"
+    JIRA_SPRINT_POLICY: "sometimes"
+`))
+		Expect(err).To(MatchError(ContainSubstring("jira.JIRA_SPRINT_POLICY")))
+	})
+
+	It("LoadConfig requires jira username unless a personal access token is set", func() {
+		_, err := process_result.LoadConfig(strings.NewReader(`
+jira:
+    JIRA_BASE_URL: "https://jira.org"
+    JIRA_PROJECT: "my project"
+    JIRA_BOARD: "p1 board"
+`))
+		Expect(err).To(MatchError(ContainSubstring("jira.JIRA_USERNAME: required key is missing or empty")))
+
+		config, err := process_result.LoadConfig(strings.NewReader(`
+jira:
+    JIRA_BASE_URL: "https://jira.org"
+    JIRA_PROJECT: "my project"
+    JIRA_BOARD: "p1 board"
+    JIRA_PERSONAL_ACCESS_TOKEN: "pat"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Jira).To(HaveKeyWithValue(process_result.JiraPersonalAccessTokenKey, "pat"))
+	})
+
+	It("LoadConfig reports invalid priority mapping", func() {
+		_, err := process_result.LoadConfig(strings.NewReader(`
+jira:
+    JIRA_BASE_URL: "https://jira.org"
+    JIRA_PROJECT: "my project"
+    JIRA_BOARD: "p1 board"
+    JIRA_USERNAME: "username"
+    JIRA_PRIORITY_BY_LABEL: "smoke"
+`))
+		Expect(err).To(MatchError(ContainSubstring("jira.JIRA_PRIORITY_BY_LABEL")))
+	})
+
+	It("LoadConfig parses regression settings", func() {
+		config, err := process_result.LoadConfig(strings.NewReader(`
+regression:
+    REGRESSION_STATE_FILE: "/tmp/state.json"
+    REGRESSION_NOTIFY_ONLY_ON_CHANGE: "true"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		c := &process_result.Options{}
+		for _, setter := range config.Options() {
+			setter(c)
+		}
+		Expect(c.RegressionInfo).ToNot(BeNil())
+		Expect(c.RegressionInfo.StateFile).To(Equal("/tmp/state.json"))
+		Expect(c.RegressionInfo.NotifyOnlyOnChange).To(BeTrue())
+	})
+
+	It("LoadConfig parses slack thread settings", func() {
+		config, err := process_result.LoadConfig(strings.NewReader(`
+slack:
+    SLACK_AUTH_TOKEN: "abc"
+    SLACK_CHANNEL: "e2e"
+    SLACK_THREADED: "true"
+    SLACK_BROADCAST_NEW_FAILURES: "true"
+    SLACK_UPLOAD_FAILURE_LOG: "true"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		c := &process_result.Options{}
+		for _, setter := range config.Options() {
+			setter(c)
+		}
+		Expect(c.SlackInfo.Threaded).To(BeTrue())
+		Expect(c.SlackInfo.BroadcastNewFailures).To(BeTrue())
+		Expect(c.SlackInfo.UploadFailureLog).To(BeTrue())
+	})
+
+	It("LoadConfig accepts slack webhook URL or channel ID instead of channel name", func() {
+		config, err := process_result.LoadConfig(strings.NewReader(`
+slack:
+    SLACK_WEBHOOK_URL: "https://hooks.slack.com/services/T0/B0/XYZ"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		c := &process_result.Options{}
+		for _, setter := range config.Options() {
+			setter(c)
+		}
+		Expect(c.SlackInfo.WebhookURL).To(Equal("https://hooks.slack.com/services/T0/B0/XYZ"))
+
+		config, err = process_result.LoadConfig(strings.NewReader(`
+slack:
+    SLACK_AUTH_TOKEN: "abc"
+    SLACK_CHANNEL_ID: "C0123"
+    SLACK_API_URL: "http://127.0.0.1:8080/api/"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		c = &process_result.Options{}
+		for _, setter := range config.Options() {
+			setter(c)
+		}
+		Expect(c.SlackInfo.ChannelID).To(Equal("C0123"))
+		Expect(c.SlackInfo.APIURL).To(Equal("http://127.0.0.1:8080/api/"))
+
+		_, err = process_result.LoadConfig(strings.NewReader(`
+slack:
+    SLACK_AUTH_TOKEN: "abc"
+`))
+		Expect(err).To(MatchError(
+			"slack.SLACK_CHANNEL: required key is missing or empty (unless SLACK_CHANNEL_ID or SLACK_WEBHOOK_URL is set)"))
+	})
+
+	It("LoadConfig parses ownership file", func() {
+		config, err := process_result.LoadConfig(strings.NewReader(`
+ownership:
+    OWNERSHIP_FILE: "/tmp/owners.yaml"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		c := &process_result.Options{}
+		for _, setter := range config.Options() {
+			setter(c)
+		}
+		Expect(c.OwnershipFile).To(Equal("/tmp/owners.yaml"))
+
+		_, err = process_result.LoadConfig(strings.NewReader("ownership: {}\n"))
+		Expect(err).To(MatchError(ContainSubstring("ownership.OWNERSHIP_FILE")))
+	})
+
+	It("LoadConfig reports invalid values", func() {
+		_, err := process_result.LoadConfig(strings.NewReader(`
+elastic:
+    ELASTIC_URL: "https://elastic.org"
+    ELASTIC_INDEX: "cs_e2e"
+    ELASTIC_BULK_SIZE: "many"
+`))
+		Expect(err).To(MatchError(`elastic.ELASTIC_BULK_SIZE: "many" is not a positive integer`))
+	})
+
+	It("LoadConfig reports unknown keys", func() {
+		_, err := process_result.LoadConfig(strings.NewReader(`
+webex:
+    WEBEX_AUTH_TOKEN: "abc"
+    WEBEX_ROOM: "e2e"
+    WEBEX_ROM: "e2e"
+`))
+		Expect(err).To(MatchError("webex.WEBEX_ROM: unknown key"))
+	})
+
+	It("LoadConfig reports unknown sections", func() {
+		_, err := process_result.LoadConfig(strings.NewReader(`
+teams:
+    TOKEN: "abc"
+`))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("LoadConfig reports missing required keys", func() {
+		_, err := process_result.LoadConfig(strings.NewReader(`
+elastic:
+    ELASTIC_URL: "https://elastic.org"
+`))
+		Expect(err).To(MatchError("elastic.ELASTIC_INDEX: required key is missing or empty"))
+	})
+
+	It("LoadConfig reports environment variables which are not set", func() {
+		_, err := process_result.LoadConfig(strings.NewReader(`
+slack:
+    SLACK_AUTH_TOKEN: "${UT_NOT_EXISTING_VARIABLE}"
+    SLACK_CHANNEL: "qa testing"
+`))
+		Expect(err).To(MatchError("slack.SLACK_AUTH_TOKEN: environment variable UT_NOT_EXISTING_VARIABLE is not set"))
+	})
+
+	It("WithConfigFile applies configuration file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(path, []byte(`
+webex:
+    WEBEX_AUTH_TOKEN: "98765aaaaa"
+    WEBEX_ROOM: "e2e result"
+`), 0600)).To(Succeed())
+
+		c := &process_result.Options{}
+		process_result.WithConfigFile(path)(c)
+		Expect(c.WebexInfo).ToNot(BeNil())
+		Expect(*c.WebexInfo).To(Equal(*getWebexInfo()))
+	})
+
+	It("ProcessReport returns error when config file cannot be loaded", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "non-existing.yaml")
+		err := process_result.ProcessReport(context.TODO(), &ginkgoTypes.Report{},
+			process_result.WithConfigFile(path))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,131 @@
+package process_result
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+)
+
+// SinkError is the error reported by a sink while processing a run.
+type SinkError struct {
+	// Sink is the name of the sink which failed
+	Sink string
+	// Index is the position of the sink among registered sinks (see WithSink).
+	// Unlike Sink, it identifies the sink even when sinks share a name.
+	Index int
+	// Err is the error returned by the sink
+	Err error
+}
+
+func (e *SinkError) Error() string {
+	return fmt.Sprintf("sink %s: %v", e.Sink, e.Err)
+}
+
+func (e *SinkError) Unwrap() error {
+	return e.Err
+}
+
+// ProcessError aggregates all errors reported by sinks while processing a run.
+type ProcessError struct {
+	// RunID is the run id
+	RunID int64
+	// Errors contains one entry per failed sink
+	Errors []*SinkError
+}
+
+func (e *ProcessError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i := range e.Errors {
+		msgs[i] = e.Errors[i].Error()
+	}
+	return fmt.Sprintf("failed to process results for run %d: %s", e.RunID, strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors reported by sinks
+func (e *ProcessError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i := range e.Errors {
+		errs[i] = e.Errors[i]
+	}
+	return errs
+}
+
+// Is reports whether any sink error matches target
+func (e *ProcessError) Is(target error) bool {
+	return utils.IsAny(e.Unwrap(), target)
+}
+
+// As finds the first sink error matching target
+func (e *ProcessError) As(target interface{}) bool {
+	return utils.AsAny(e.Unwrap(), target)
+}
+
+// sinkStatus is the status of a sink as reported in the status file.
+type sinkStatus struct {
+	Name      string `json:"name"`
+	Succeeded bool   `json:"succeeded"`
+	Error     string `json:"error,omitempty"`
+}
+
+// runStatus is the content of the status file.
+type runStatus struct {
+	RunID     int64        `json:"runID"`
+	Succeeded bool         `json:"succeeded"`
+	Sinks     []sinkStatus `json:"sinks"`
+}
+
+// Namer can optionally be implemented by a Sink to provide the name used
+// in errors and in the status file.
+type Namer interface {
+	Name() string
+}
+
+// getSinkName returns the name of the sink
+func getSinkName(sink Sink) string {
+	if n, ok := sink.(Namer); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", sink)
+}
+
+// writeStatusFile writes, in JSON format, the outcome of each sink.
+func writeStatusFile(path string, runID int64, sinks []Sink, processErr *ProcessError) error {
+	status := runStatus{
+		RunID:     runID,
+		Succeeded: processErr == nil,
+		Sinks:     make([]sinkStatus, len(sinks)),
+	}
+
+	for i := range sinks {
+		status.Sinks[i] = sinkStatus{
+			Name:      getSinkName(sinks[i]),
+			Succeeded: true,
+		}
+	}
+
+	// Sinks can share a name: failures are matched by sink index. A sink can fail
+	// more than once when more than one report is processed.
+	if processErr != nil {
+		for i := range processErr.Errors {
+			index := processErr.Errors[i].Index
+			if index < 0 || index >= len(status.Sinks) {
+				continue
+			}
+			if !status.Sinks[index].Succeeded {
+				status.Sinks[index].Error += "; "
+			}
+			status.Sinks[index].Succeeded = false
+			status.Sinks[index].Error += processErr.Errors[i].Err.Error()
+		}
+	}
+
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
@@ -6,5 +6,22 @@ var (
 	VerifySlackInfo   = verifySlackInfo
 	VerifyJiraInfo    = verifyJiraInfo
 
-	PrepareMessage = prepareMessage
+	PrepareMessage           = prepareMessage
+	PrepareSlackMessage      = prepareSlackMessage
+	PrepareRegressionMessage = prepareRegressionMessage
+	SlackMentions            = slackMentions
+	WebexMentions            = webexMentions
+
+	RunSinks          = runSinks
+	WriteStatusFile   = writeStatusFile
+	ShouldNotify      = shouldNotify
+	CountPassingSince = countPassingSince
 )
+
+func (i *Options) GetSinks() []Sink {
+	return i.getSinks()
+}
+
+func (i *Options) LoadOwnership() error {
+	return i.loadOwnership()
+}
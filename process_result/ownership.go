@@ -0,0 +1,93 @@
+package process_result
+
+import (
+	"fmt"
+	"strings"
+
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ownership_helper"
+)
+
+// mentionFunc returns, in the format of a chat sink, the mentions of an owner
+type mentionFunc func(owner *ownership_helper.Owner) []string
+
+// WithOwnershipFile maps tests to owners using the ownership file at path.
+// A test matches an owner by spec file path glob, container text regular expression
+// or label. When more than one rule matches, the last one wins (as in CODEOWNERS).
+//
+//	owners:
+//	- paths: ["test/e2e/network/"]
+//	  containers: ["^Networking"]
+//	  labels: ["network"]
+//	  jiraAssignee: alice
+//	  jiraComponent: Networking
+//	  slack: ["U0123ABCD", "S0123ABCD"]
+//	  webex: ["alice@org.com"]
+//
+// Jira issues filed for tests with no maintainer are assigned to owner jira assignee
+// and owner jira component takes precedence over JiraInfo.Component.
+// Slack and Webex messages mention the owners of failed tests.
+// Any error loading the file is returned by Register (or ProcessReport).
+func WithOwnershipFile(path string) Option {
+	return func(args *Options) {
+		args.OwnershipFile = path
+	}
+}
+
+// loadOwnership loads the ownership file, if any
+func (i *Options) loadOwnership() error {
+	if i.OwnershipFile == "" {
+		return nil
+	}
+
+	ownership, err := ownership_helper.LoadFile(i.OwnershipFile)
+	if err != nil {
+		return err
+	}
+	i.ownership = ownership
+	return nil
+}
+
+// getOwnerText returns the text mentioning the owner of a failed test. Empty if test has
+// no owner or mention is nil.
+func getOwnerText(c *Options, specReport *ginkgoTypes.SpecReport, mention mentionFunc) string {
+	if mention == nil {
+		return ""
+	}
+	owner := c.ownership.GetOwner(specReport)
+	if owner == nil {
+		return ""
+	}
+	mentions := mention(owner)
+	if len(mentions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" cc %s", strings.Join(mentions, " "))
+}
+
+// slackMentions returns the Slack mentions of owner. Ids starting with S are user
+// groups, any other id is a user. Values already in Slack format (<...>) are kept.
+func slackMentions(owner *ownership_helper.Owner) []string {
+	mentions := make([]string, 0, len(owner.Slack))
+	for _, id := range owner.Slack {
+		switch {
+		case strings.HasPrefix(id, "<"):
+			mentions = append(mentions, id)
+		case strings.HasPrefix(id, "S"):
+			mentions = append(mentions, fmt.Sprintf("<!subteam^%s>", id))
+		default:
+			mentions = append(mentions, fmt.Sprintf("<@%s>", id))
+		}
+	}
+	return mentions
+}
+
+// webexMentions returns the Webex mentions of owner
+func webexMentions(owner *ownership_helper.Owner) []string {
+	mentions := make([]string, 0, len(owner.Webex))
+	for _, email := range owner.Webex {
+		mentions = append(mentions, fmt.Sprintf("<@personEmail:%s>", email))
+	}
+	return mentions
+}
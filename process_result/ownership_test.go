@@ -0,0 +1,63 @@
+package process_result_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ownership_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/process_result"
+)
+
+var _ = Describe("Ownership", func() {
+	var c *process_result.Options
+
+	BeforeEach(func() {
+		path := filepath.Join(GinkgoT().TempDir(), "owners.yaml")
+		Expect(os.WriteFile(path, []byte(`
+owners:
+- containers: ["^Verify list"]
+  slack: ["U0ALICE", "S0LISTS"]
+  webex: ["alice@org.com"]
+- containers: ["^Verify Labels"]
+  jiraAssignee: bob
+`), 0600)).To(Succeed())
+
+		c = &process_result.Options{}
+		process_result.WithRunID(int64(42))(c)
+		process_result.WithOwnershipFile(path)(c)
+		Expect(c.LoadOwnership()).To(Succeed())
+	})
+
+	It("Slack messages mention owners of failed tests", func() {
+		report := ginkgoTypes.Report{SpecReports: getSpecReport()}
+		message := process_result.PrepareMessage(&report, c, nil, process_result.SlackMentions)
+		Expect(message).To(ContainSubstring(
+			"Test: \"Verify list methods return ordered list\" failed in run 42  cc <@U0ALICE> <!subteam^S0LISTS>  \n"))
+		// owner with no slack mention
+		Expect(message).To(ContainSubstring(
+			"Test: \"Verify Labels Filter on Labels return correct data based on labels\" failed in run 42   \n"))
+	})
+
+	It("Webex messages mention owners of failed tests", func() {
+		report := ginkgoTypes.Report{SpecReports: getSpecReport()}
+		message := process_result.PrepareMessage(&report, c, nil, process_result.WebexMentions)
+		Expect(message).To(ContainSubstring(
+			"Test: \"Verify list methods return ordered list\" failed in run 42  cc <@personEmail:alice@org.com>  \n"))
+	})
+
+	It("Slack mentions already in Slack format are kept", func() {
+		Expect(process_result.SlackMentions(&ownership_helper.Owner{Slack: []string{"<!here>", "U0BOB"}})).To(Equal([]string{"<!here>", "<@U0BOB>"}))
+	})
+
+	It("ProcessReport returns an error when ownership file cannot be loaded", func() {
+		report := &ginkgoTypes.Report{SpecReports: getSpecReport()}
+		err := process_result.ProcessReport(context.TODO(), report,
+			process_result.WithOwnershipFile(filepath.Join(GinkgoT().TempDir(), "missing.yaml")))
+		Expect(err).To(MatchError(ContainSubstring("missing.yaml")))
+	})
+})
@@ -3,6 +3,7 @@ package process_result
 import (
 	"context"
 	"fmt"
+	"io"
 
 	. "github.com/onsi/ginkgo/v2" // nolint: golint,stylecheck // ginkgo pattern
 	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
@@ -12,6 +13,7 @@ import (
 	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/elastic_helper"
 	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
 	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/jira_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ownership_helper"
 	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/slack_helper"
 	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
 	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/webex_helper"
@@ -22,9 +24,35 @@ type Options struct {
 	WebexInfo   *WebexInfo
 	SlackInfo   *SlackInfo
 	JiraInfo    *JiraInfo
+	Sinks       []Sink // custom sinks, processed after the built-in ones
 	RunID       int64
 	DryRun      bool
 	EnableLogs  bool
+
+	// RegressionInfo, when set, enables regression detection
+	RegressionInfo *RegressionInfo
+
+	// FailOnSinkError, when set, causes the test-suite to fail if any sink
+	// fails to process results.
+	FailOnSinkError bool
+
+	// StatusFile, when set, is the path of the file where the outcome of each
+	// sink is written in JSON format.
+	StatusFile string
+
+	// LogWriter, when set, is where logs are written to instead of ginkgo By.
+	// Required to enable logs when processing a report outside of a running test-suite.
+	LogWriter io.Writer
+
+	// OwnershipFile, when set, is the path of the file mapping tests to owners.
+	// See WithOwnershipFile.
+	OwnershipFile string
+
+	// ownership is loaded from OwnershipFile
+	ownership *ownership_helper.Ownership
+
+	// err is set by setters which can fail (for instance WithConfigFile)
+	err error
 }
 
 type WebexInfo struct {
@@ -33,24 +61,104 @@ type WebexInfo struct {
 }
 
 type SlackInfo struct {
-	AuthToken string // slack auth token
-	Channel   string // slack channel name
+	AuthToken string // slack auth token. Not needed with WebhookURL
+	Channel   string // slack channel name. Not needed with ChannelID or WebhookURL
+	ChannelID string // slack channel ID. When set, channel is not looked up by name
+	// WebhookURL is the URL of an incoming webhook. When set, messages are posted to it: neither
+	// auth token nor channel are needed. Webhooks cannot reply in threads, so with Threaded replies
+	// are posted as separate messages.
+	WebhookURL string
+	// APIURL is the slack API URL. Default to https://slack.com/api/
+	APIURL string
+	// Threaded, when set, posts a short summary message and then the details of each failed
+	// test (failure message, stack trace and GinkgoWriter output) as replies in its thread
+	Threaded bool
+	// BroadcastNewFailures, when set along with Threaded and regression detection, also sends
+	// the replies of new failures to the channel
+	BroadcastNewFailures bool
+	// UploadFailureLog, when set, uploads a text file with failure message, stack trace and
+	// GinkgoWriter output of all failed tests to the channel and links it from the message.
+	// Requires AuthToken (files cannot be uploaded to incoming webhooks) and files:write scope.
+	UploadFailureLog bool
 }
 
-type ElasticInfo struct {
-	URL   string // elastic DB URL
-	Index string // elastic DB Index
-}
+// ElasticInfo contains the elastic DB configuration. It is shared with the query package.
+type ElasticInfo = elastic_helper.ElasticInfo
+
+// JiraSprintPolicy defines which sprint, if any, jira issues are moved to
+type JiraSprintPolicy string
+
+const (
+	// JiraSprintPolicyNone does not move issues to any sprint. No board is needed, which
+	// makes it suitable for Kanban and team-managed projects.
+	JiraSprintPolicyNone = JiraSprintPolicy(jira_helper.SprintPolicyNone)
+	// JiraSprintPolicyActive moves filed and commented issues to the active sprint of the board.
+	// If board has no active sprint (or does not support sprints), issues are not moved.
+	JiraSprintPolicyActive = JiraSprintPolicy(jira_helper.SprintPolicyActive)
+	// JiraSprintPolicyNamed moves filed and commented issues to the active or future sprint
+	// named JiraInfo.SprintName
+	JiraSprintPolicyNamed = JiraSprintPolicy(jira_helper.SprintPolicyNamed)
+	// JiraSprintPolicyNewIssues moves only filed issues to the active sprint of the board. Issues
+	// which are only commented are not dragged back into the current sprint.
+	JiraSprintPolicyNewIssues = JiraSprintPolicy(jira_helper.SprintPolicyNewIssues)
+)
 
 type JiraInfo struct {
 	BaseURL   string // jira base URL
 	Project   string // jira Project name
-	Board     string // jira Board Name
-	Component string // if not empty, any jira filed issue will have this as component
-	Username  string // jira username. This is required field. When a test fails, a bug is filed.
-	// If a bug is already open for the failed test, no new bug will be open. Simply
-	// a new comment will added. jql to search for open bug uses also reporter = username
+	Board     string // jira Board Name. Required unless SprintPolicy is JiraSprintPolicyNone
+	Component string // if not empty, any jira filed issue will have this (or its owner, see WithOwnershipFile) as component
+	Username  string // jira username (the account email on jira Cloud). Required unless PersonalAccessToken is set.
+	// When a test fails, a bug is filed. If a bug is already open for the failed test, no new bug will be open.
+	// Simply a new comment will added. jql to search for open bug uses also reporter = username
+	// (reporter = accountId on jira Cloud)
 	Password string // jira password
+	// APIToken is the jira Cloud API token, used instead of Password along with Username
+	APIToken string
+	// PersonalAccessToken is the jira Server/Data Center personal access token. When set, it is
+	// sent as Bearer token and username is read from jira.
+	PersonalAccessToken string
+	// Cloud must be set for jira Cloud. Descriptions and comments are then sent in Atlassian
+	// Document Format (REST API v3), reporter and assignee are identified by accountId.
+	// Test maintainer must be an email or display name jira Cloud can find the user by.
+	Cloud bool
+
+	// AttachOutput, when set, attaches captured GinkgoWriter output and stdout/stderr of failed tests
+	// to their issues
+	AttachOutput bool
+	// ArtifactEntries are the names of report entries (see AddReportEntry) whose value is the path
+	// of a file, e.g. a cluster dump or a screenshot, to attach to the issue of a failed test
+	ArtifactEntries []string
+	// MaxAttachmentSize is the maximum size, in bytes, of an attachment. Outputs are truncated (their
+	// end is kept) and larger files are not attached. Default to 1MB.
+	MaxAttachmentSize int
+	// ResolveAfterPasses, if positive, is the number of consecutive runs a test must pass in
+	// for its open issue to be resolved. Values greater than one require elastic, which is
+	// where previous runs results are read from. Zero disables auto-resolve.
+	ResolveAfterPasses int
+	// ResolvedStatus is the status issues are transitioned to when resolved. Default to Resolved.
+	ResolvedStatus string
+
+	IssueType string // type of filed issues. Default to Bug
+	Priority  string // priority of filed issues. Default to P1
+	// SerialPriority, if not empty, is the priority of issues filed for Serial tests
+	SerialPriority string
+	// PriorityByLabel maps test labels to priorities. Priority of the first test label found
+	// takes precedence over Priority and SerialPriority.
+	PriorityByLabel map[string]string
+	Labels          []string // labels added to filed issues
+	// CustomFields maps custom field ids (e.g. customfield_10010) to the value set on filed issues.
+	// Values are text/template templates, e.g. "Found in run {{ .RunID }}". See jira_helper.IssueTemplateData
+	// for the available data. A value which is a JSON object or array is sent as such.
+	CustomFields map[string]string
+	// DoneStatuses are the statuses of issues which are not open anymore. Default to Resolved and Closed.
+	DoneStatuses []string
+
+	// SprintPolicy defines which sprint, if any, issues are moved to. Default to JiraSprintPolicyActive
+	// if Board is set, JiraSprintPolicyNone otherwise.
+	SprintPolicy JiraSprintPolicy
+	// SprintName is the name of the sprint issues are moved to with JiraSprintPolicyNamed
+	SprintName string
 }
 
 type Option func(*Options)
@@ -61,6 +169,13 @@ func WithLogs() Option {
 	}
 }
 
+// WithLogWriter writes logs to w instead of using ginkgo By.
+func WithLogWriter(w io.Writer) Option {
+	return func(args *Options) {
+		args.LogWriter = w
+	}
+}
+
 func WithRunID(runID int64) Option {
 	return func(args *Options) {
 		args.RunID = runID
@@ -74,6 +189,20 @@ func WithDryRun() Option {
 	}
 }
 
+// WithFailOnSinkError makes the test-suite fail if any sink fails to process results.
+func WithFailOnSinkError() Option {
+	return func(args *Options) {
+		args.FailOnSinkError = true
+	}
+}
+
+// WithStatusFile writes the outcome of each sink, in JSON format, to path.
+func WithStatusFile(path string) Option {
+	return func(args *Options) {
+		args.StatusFile = path
+	}
+}
+
 func WithElastic(info ElasticInfo) Option {
 	return func(args *Options) {
 		args.ElasticInfo = &info
@@ -99,106 +228,202 @@ func WithJira(info JiraInfo) Option {
 }
 
 // Register register ReportAfterSuite (named afterSuiteReport) when called.
+// Every sink (built-in and custom) is verified before registering.
 func Register(ctx context.Context, setters ...Option) error {
+	c, sinks, err := setup(ctx, setters...)
+	if err != nil {
+		return err
+	}
+
+	afterSuiteReport := func(report ginkgoTypes.Report) {
+		processErr := c.process(context.TODO(), &report, sinks)
+		if processErr != nil && c.FailOnSinkError {
+			Fail(processErr.Error())
+		}
+	}
+
+	ReportAfterSuite("afterSuiteReport", afterSuiteReport)
+	return nil
+}
+
+// ProcessReport processes a test-suite report outside of a running test-suite,
+// for instance a report previously saved with ginkgo --json-report.
+// Every sink (built-in and custom) is verified before processing.
+// Returns a *ProcessError if any sink fails.
+func ProcessReport(ctx context.Context, report *ginkgoTypes.Report, setters ...Option) error {
+	return ProcessReports(ctx, []ginkgoTypes.Report{*report}, setters...)
+}
+
+// ProcessReports processes, as part of the same run, the reports of one or more
+// test-suites, for instance the ones saved with ginkgo --json-report when running
+// several suites. Sinks are verified once, then each report is processed in order.
+// The status file, if requested, reports the outcome of each sink across all reports.
+// Returns a *ProcessError if any sink fails on any report.
+func ProcessReports(ctx context.Context, reports []ginkgoTypes.Report, setters ...Option) error {
+	c, sinks, err := setup(ctx, setters...)
+	if err != nil {
+		return err
+	}
+
+	var processErr *ProcessError
+	for i := range reports {
+		if reportErr := c.processReport(ctx, &reports[i], sinks); reportErr != nil {
+			if processErr == nil {
+				processErr = &ProcessError{RunID: c.RunID}
+			}
+			processErr.Errors = append(processErr.Errors, reportErr.Errors...)
+		}
+	}
+
+	c.writeStatus(sinks, processErr)
+
+	if processErr != nil {
+		return processErr
+	}
+	return nil
+}
+
+// setup applies all setters and verifies all sinks.
+func setup(ctx context.Context, setters ...Option) (*Options, []Sink, error) {
 	c := &Options{}
 
 	for _, setter := range setters {
 		setter(c)
 	}
 
-	if c.DryRun {
-		utils.Init(true)
+	if c.err != nil {
+		return nil, nil, c.err
 	}
 
-	if c.ElasticInfo != nil {
-		if err := verifyElasticInfo(ctx, c); err != nil {
-			return err
-		}
+	utils.SetWriter(c.LogWriter)
+
+	if err := c.loadOwnership(); err != nil {
+		return nil, nil, err
 	}
 
-	if c.WebexInfo != nil {
-		if err := verifyWebexInfo(ctx, c); err != nil {
-			return err
-		}
+	if c.DryRun {
+		utils.Init(true)
 	}
 
-	if c.SlackInfo != nil {
-		if err := verifySlackInfo(ctx, c); err != nil {
-			return err
+	if c.RegressionInfo != nil {
+		if err := verifyRegressionInfo(c); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	if c.JiraInfo != nil {
-		if err := verifyJiraInfo(ctx, c); err != nil {
-			return err
+	sinks := c.getSinks()
+	for i := range sinks {
+		if err := sinks[i].Verify(ctx); err != nil {
+			return nil, nil, err
 		}
 	}
 
 	utils.Init(c.EnableLogs)
 
-	afterSuiteReport := func(report ginkgoTypes.Report) {
-		if c.ElasticInfo != nil {
-			utils.Byf(fmt.Sprintf("Save results to elastic db. Run %d", c.RunID))
-			elastic_helper.StoreResults(&report, c.RunID, c.getElasticInfo())
-		}
-
-		var openIssues []jira.Issue
-		if c.JiraInfo != nil {
-			utils.Byf(fmt.Sprintf("File jira issue for failed tests. Run %d", c.RunID))
-			_ = jira_helper.FileJiraIssuesForFailedTests(context.TODO(), &report, c.RunID, c.getJiraInfo())
-
-			openIssues, _ = jira_helper.GetOpenE2EJiraIssue(context.TODO(), c.getJiraInfo())
-		}
+	return c, sinks, nil
+}
 
-		msg := prepareMessage(&report, c, openIssues)
+// process runs all sinks against report and, if requested, writes the status file.
+func (i *Options) process(ctx context.Context, report *ginkgoTypes.Report, sinks []Sink) *ProcessError {
+	processErr := i.processReport(ctx, report, sinks)
+	i.writeStatus(sinks, processErr)
+	return processErr
+}
 
-		if c.WebexInfo != nil {
-			utils.Byf(fmt.Sprintf("Send failed tests notification to webex room %s", c.WebexInfo.Room))
-			sendWebexNotification(&report, c, msg)
-		}
+// processReport runs all sinks against report
+func (i *Options) processReport(ctx context.Context, report *ginkgoTypes.Report, sinks []Sink) *ProcessError {
+	runInfo := &RunInfo{
+		RunID:  i.RunID,
+		DryRun: i.DryRun,
+	}
 
-		if c.SlackInfo != nil {
-			utils.Byf(fmt.Sprintf("Send failed tests notification to slack channel %s", c.SlackInfo.Channel))
-			sendSlackNotification(&report, c, msg)
+	if i.RegressionInfo != nil {
+		regressions, err := i.getRegressions(ctx, report)
+		if err != nil {
+			utils.Byf(fmt.Sprintf("Failed to detect regressions. Run %d. Error: %v", i.RunID, err))
+		} else {
+			runInfo.Regressions = regressions
 		}
 	}
 
-	ReportAfterSuite("afterSuiteReport", afterSuiteReport)
-	return nil
-}
+	processErr := runSinks(ctx, report, sinks, runInfo)
 
-// sendWebexNotification send a message for each failed test.
-func sendWebexNotification(report *ginkgoTypes.Report, c *Options, msg string) {
-	utils.Byf("Eventually sending Webex notifications")
+	if runInfo.Regressions != nil {
+		i.saveRegressions(report, runInfo.Regressions)
+	}
 
-	webex_helper.SendWebexMessage(c.getWebexInfo(), msg)
+	return processErr
 }
 
-// sendSlackNotification send a message for each failed test.
-func sendSlackNotification(report *ginkgoTypes.Report, c *Options, msg string) {
-	utils.Byf("Eventually sending Slack notifications")
+// writeStatus writes, if requested, the status file
+func (i *Options) writeStatus(sinks []Sink, processErr *ProcessError) {
+	if i.StatusFile == "" {
+		return
+	}
 
-	slack_helper.SendSlackMessage(c.getSlackInfo(), msg)
+	if err := writeStatusFile(i.StatusFile, i.RunID, sinks, processErr); err != nil {
+		utils.Byf(fmt.Sprintf("Failed to write status file %s. Error: %v", i.StatusFile, err))
+	}
 }
 
-func prepareMessage(report *ginkgoTypes.Report, c *Options, openIssues []jira.Issue) string {
+// prepareMessage returns the markdown message listing failed tests, each with the
+// corresponding jira issue if any and, if mention is not nil, its owner mentions,
+// followed by flaky tests.
+func prepareMessage(report *ginkgoTypes.Report, c *Options, openIssues []jira.Issue, mention mentionFunc) string {
 	msg := ""
 	for i := range report.SpecReports {
-		specReport := report.SpecReports[i]
+		specReport := &report.SpecReports[i]
 		if specReport.Failed() {
-			testText := specReport.FullText()
-			if testText == "" {
-				testText = ginkgo_helper.GetSummary(&specReport)
-			}
-			msg += fmt.Sprintf("Test: %q failed in run %d ", testText, c.RunID)
-			if openIssue := jira_helper.FindExistingIssue(openIssues, &specReport); openIssue != nil {
-				msg += fmt.Sprintf("current jira issue %s", openIssue.Key)
-			}
-			msg += "  \n"
+			msg += fmt.Sprintf("Test: %q failed in run %d ", getTestText(specReport), c.RunID)
+			msg += getIssueText(openIssues, specReport) + getOwnerText(c, specReport, mention) + "  \n"
 		}
 	}
 
-	return msg
+	return msg + getFlakyMessage(report, c)
+}
+
+// getMessage returns the message to send for a run. When regression detection is
+// enabled, failed tests are split into new failures and still failing tests.
+// Owners of failed tests are mentioned using mention.
+func getMessage(report *ginkgoTypes.Report, c *Options, runInfo *RunInfo, mention mentionFunc) string {
+	if runInfo.Regressions != nil {
+		return prepareRegressionMessage(report, c, runInfo.OpenIssues, runInfo.Regressions, mention)
+	}
+	return prepareMessage(report, c, runInfo.OpenIssues, mention)
+}
+
+// getFlakyMessage returns the markdown section listing flaky tests. Empty if there are none.
+func getFlakyMessage(report *ginkgoTypes.Report, c *Options) string {
+	flaky := ""
+	for i := range report.SpecReports {
+		specReport := &report.SpecReports[i]
+		if !specReport.Failed() && ginkgo_helper.IsFlaky(specReport) {
+			flaky += fmt.Sprintf("Test: %q passed in run %d after %d attempts  \n",
+				getTestText(specReport), c.RunID, specReport.NumAttempts)
+		}
+	}
+
+	if flaky == "" {
+		return ""
+	}
+	return "**Flaky tests**  \n" + flaky
+}
+
+// getTestText returns the text used to identify a test in messages
+func getTestText(specReport *ginkgoTypes.SpecReport) string {
+	testText := specReport.FullText()
+	if testText == "" {
+		testText = ginkgo_helper.GetSummary(specReport)
+	}
+	return testText
+}
+
+// getIssueText returns the text referencing the open jira issue for a test, if any
+func getIssueText(openIssues []jira.Issue, specReport *ginkgoTypes.SpecReport) string {
+	if openIssue := jira_helper.FindExistingIssue(openIssues, specReport); openIssue != nil {
+		return fmt.Sprintf("current jira issue %s", openIssue.Key)
+	}
+	return ""
 }
 
 func (i *Options) getWebexInfo() *webex_helper.WebexInfo {
@@ -211,21 +436,59 @@ func (i *Options) getWebexInfo() *webex_helper.WebexInfo {
 
 func (i *Options) getSlackInfo() *slack_helper.SlackInfo {
 	return &slack_helper.SlackInfo{
-		AuthToken: i.SlackInfo.AuthToken,
-		Channel:   i.SlackInfo.Channel,
-		DryRun:    i.DryRun,
+		AuthToken:  i.SlackInfo.AuthToken,
+		Channel:    i.SlackInfo.Channel,
+		ChannelID:  i.SlackInfo.ChannelID,
+		WebhookURL: i.SlackInfo.WebhookURL,
+		APIURL:     i.SlackInfo.APIURL,
+		DryRun:     i.DryRun,
 	}
 }
 
 func (i *Options) getElasticInfo() *elastic_helper.ElasticInfo {
-	return &elastic_helper.ElasticInfo{
-		URL:    i.ElasticInfo.URL,
-		Index:  i.ElasticInfo.Index,
-		DryRun: i.DryRun,
-	}
+	return i.ElasticInfo
 }
 
+// Defaults for JiraInfo fields
+const (
+	defaultResolvedStatus = "Resolved"
+	defaultIssueType      = "Bug"
+	defaultPriority       = "P1"
+
+	defaultMaxAttachmentSize = 1024 * 1024
+)
+
+var defaultDoneStatuses = []string{"Resolved", "Closed"}
+
 func (i *Options) getJiraInfo() *jira_helper.JiraInfo {
+	resolvedStatus := i.JiraInfo.ResolvedStatus
+	if resolvedStatus == "" {
+		resolvedStatus = defaultResolvedStatus
+	}
+	issueType := i.JiraInfo.IssueType
+	if issueType == "" {
+		issueType = defaultIssueType
+	}
+	priority := i.JiraInfo.Priority
+	if priority == "" {
+		priority = defaultPriority
+	}
+	doneStatuses := i.JiraInfo.DoneStatuses
+	if len(doneStatuses) == 0 {
+		doneStatuses = defaultDoneStatuses
+	}
+	maxAttachmentSize := i.JiraInfo.MaxAttachmentSize
+	if maxAttachmentSize <= 0 {
+		maxAttachmentSize = defaultMaxAttachmentSize
+	}
+	sprintPolicy := i.JiraInfo.SprintPolicy
+	if sprintPolicy == "" {
+		sprintPolicy = JiraSprintPolicyNone
+		if i.JiraInfo.Board != "" {
+			sprintPolicy = JiraSprintPolicyActive
+		}
+	}
+
 	return &jira_helper.JiraInfo{
 		BaseURL:   i.JiraInfo.BaseURL,
 		Project:   i.JiraInfo.Project,
@@ -234,11 +497,34 @@ func (i *Options) getJiraInfo() *jira_helper.JiraInfo {
 		Username:  i.JiraInfo.Username,
 		Password:  i.JiraInfo.Password,
 		DryRun:    i.DryRun,
+		Ownership: i.ownership,
+
+		APIToken:            i.JiraInfo.APIToken,
+		PersonalAccessToken: i.JiraInfo.PersonalAccessToken,
+		Cloud:               i.JiraInfo.Cloud,
+
+		AttachOutput:      i.JiraInfo.AttachOutput,
+		ArtifactEntries:   i.JiraInfo.ArtifactEntries,
+		MaxAttachmentSize: maxAttachmentSize,
+
+		SprintPolicy: jira_helper.SprintPolicy(sprintPolicy),
+		SprintName:   i.JiraInfo.SprintName,
+
+		ResolveAfterPasses: i.JiraInfo.ResolveAfterPasses,
+		ResolvedStatus:     resolvedStatus,
+
+		IssueType:       issueType,
+		Priority:        priority,
+		SerialPriority:  i.JiraInfo.SerialPriority,
+		PriorityByLabel: i.JiraInfo.PriorityByLabel,
+		Labels:          i.JiraInfo.Labels,
+		CustomFields:    i.JiraInfo.CustomFields,
+		DoneStatuses:    doneStatuses,
 	}
 }
 
 func verifyElasticInfo(ctx context.Context, c *Options) error {
-	if err := elastic_helper.VerifyInfo(ctx, c.getElasticInfo()); err != nil {
+	if err := elastic_helper.VerifyInfo(ctx, c.getElasticInfo(), c.DryRun); err != nil {
 		return fmt.Errorf("failed to verify elastic info. Error: %v", err)
 	}
 	return nil
@@ -252,6 +538,9 @@ func verifyWebexInfo(ctx context.Context, c *Options) error {
 }
 
 func verifySlackInfo(ctx context.Context, c *Options) error {
+	if c.SlackInfo.UploadFailureLog && c.SlackInfo.WebhookURL != "" {
+		return fmt.Errorf("failed to verify slack info. Error: failure log cannot be uploaded to an incoming webhook")
+	}
 	if err := slack_helper.VerifyInfo(ctx, c.getSlackInfo()); err != nil {
 		return fmt.Errorf("failed to verify slack info. Error: %v", err)
 	}
@@ -259,6 +548,10 @@ func verifySlackInfo(ctx context.Context, c *Options) error {
 }
 
 func verifyJiraInfo(ctx context.Context, c *Options) error {
+	if c.JiraInfo.ResolveAfterPasses > 1 && c.ElasticInfo == nil {
+		return fmt.Errorf("failed to verify jira info. Error: resolving issues after %d passing runs requires elastic",
+			c.JiraInfo.ResolveAfterPasses)
+	}
 	if err := jira_helper.VerifyInfo(ctx, c.getJiraInfo()); err != nil {
 		return fmt.Errorf("failed to verify jira info. Error: %v", err)
 	}
@@ -3,6 +3,7 @@ package process_result_test
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/andygrunwald/go-jira"
@@ -64,10 +65,35 @@ var _ = Describe("PrepareMessage", func() {
 		setter := process_result.WithRunID(int64(65512))
 		setter(c)
 
-		message := process_result.PrepareMessage(&report, c, nil)
+		message := process_result.PrepareMessage(&report, c, nil, nil)
 		Expect(message).To(ContainSubstring("Test: \"Verify Labels Filter on Labels return correct data based on labels\" failed in run 65512"))
 		Expect(message).To(ContainSubstring("Test: \"Verify list methods return ordered list\" failed in run 65512"))
 		Expect(message).To(ContainSubstring("Test: \"SynchronizedBeforeSuite\" failed in run 65512"))
+		Expect(message).ToNot(ContainSubstring("Flaky tests"))
+	})
+
+	It("Prepare message with a separate section for flaky tests", func() {
+		specReports := getSpecReport()
+		specReports = append(specReports, ginkgoTypes.SpecReport{
+			LeafNodeType:            ginkgoTypes.NodeTypeIt,
+			State:                   ginkgoTypes.SpecStatePassed,
+			NumAttempts:             3,
+			RunTime:                 time.Second,
+			LeafNodeText:            "return sorted list",
+			ContainerHierarchyTexts: []string{"Verify list methods"},
+		})
+		report := ginkgoTypes.Report{
+			SpecReports: specReports,
+		}
+		c := &process_result.Options{}
+		setter := process_result.WithRunID(int64(887))
+		setter(c)
+
+		message := process_result.PrepareMessage(&report, c, nil, nil)
+		Expect(message).To(ContainSubstring("Test: \"Verify list methods return ordered list\" failed in run 887"))
+		Expect(message).To(ContainSubstring("**Flaky tests**"))
+		Expect(message).To(ContainSubstring("Test: \"Verify list methods return sorted list\" passed in run 887 after 3 attempts"))
+		Expect(strings.Index(message, "Flaky tests")).To(BeNumerically(">", strings.Index(message, "failed in run")))
 	})
 
 	It("Prepare correct message when Jira issues are not present", func() {
@@ -100,7 +126,7 @@ var _ = Describe("PrepareMessage", func() {
 			}
 		}
 
-		message := process_result.PrepareMessage(&report, c, openIssue)
+		message := process_result.PrepareMessage(&report, c, openIssue, nil)
 		for i := range expected {
 			Expect(message).To(ContainSubstring(expected[i]))
 		}
@@ -131,6 +157,20 @@ var _ = Describe("Setters", func() {
 		Expect(c.DryRun).To(BeTrue())
 	})
 
+	It("WithFailOnSinkError sets FailOnSinkError", func() {
+		f := process_result.WithFailOnSinkError()
+		c := &process_result.Options{}
+		f(c)
+		Expect(c.FailOnSinkError).To(BeTrue())
+	})
+
+	It("WithStatusFile sets StatusFile", func() {
+		f := process_result.WithStatusFile("/tmp/status.json")
+		c := &process_result.Options{}
+		f(c)
+		Expect(c.StatusFile).To(Equal("/tmp/status.json"))
+	})
+
 	It("WithElastic sets ElasticInfo", func() {
 		f := process_result.WithElastic(*getElasticInfo())
 		c := &process_result.Options{
@@ -0,0 +1,358 @@
+package process_result
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+
+	"github.com/andygrunwald/go-jira"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/elastic_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+)
+
+// regressionHistoryRuns is the number of previous runs searched, in elastic, to find
+// the run a still failing test has been failing since
+const regressionHistoryRuns = 30
+
+// RegressionInfo configures regression detection. Tests failed in current run are
+// compared with the ones failed in previous run and split into new failures, still
+// failing tests and tests fixed since previous run.
+// Previous run results are read from elastic, if configured, or from StateFile otherwise.
+type RegressionInfo struct {
+	// StateFile is the file failed tests are read from and written to when elastic
+	// is not configured. It is not written on dry run.
+	StateFile string
+	// NotifyOnlyOnChange, when set, sends Webex and Slack notifications only if
+	// there are new failures or fixed tests.
+	NotifyOnlyOnChange bool
+}
+
+// WithRegressionDetection enables regression detection.
+func WithRegressionDetection(info RegressionInfo) Option {
+	return func(args *Options) {
+		args.RegressionInfo = &info
+	}
+}
+
+// RegressionTest is a test listed in Regressions
+type RegressionTest struct {
+	// Name is the test name, as stored in elastic
+	Name string
+	// Text is the test full text
+	Text string
+	// FailingSince is the run test has been failing since. Equal to current run for
+	// new failures. Not set for fixed tests.
+	FailingSince int64
+}
+
+// Regressions contains failed tests of current run compared with previous run
+type Regressions struct {
+	// PreviousRunID is the id of previous run. 0 if there is no previous run.
+	PreviousRunID int64
+	// NewFailures contains tests which failed in current run but not in previous one
+	NewFailures []RegressionTest
+	// StillFailing contains tests which failed in both current and previous run
+	StillFailing []RegressionTest
+	// Fixed contains tests which failed in previous run and passed in current one
+	Fixed []RegressionTest
+}
+
+// Changed returns true if there are new failures or fixed tests
+func (r *Regressions) Changed() bool {
+	return len(r.NewFailures) != 0 || len(r.Fixed) != 0
+}
+
+// regressionState contains the failed tests of a run
+type regressionState struct {
+	RunID int64 `json:"runID"`
+	// Failures contains, per failed test name, the run test has been failing since
+	Failures map[string]int64 `json:"failures"`
+}
+
+// regressionStateFile is what is stored in RegressionInfo.StateFile
+type regressionStateFile struct {
+	// Suites contains, per suite path, the failed tests of the last run of the suite.
+	// Keying by suite path lets several suites processed in the same run share the file.
+	Suites map[string]*regressionState `json:"suites,omitempty"`
+	// RunID and Failures are written by versions storing a single state for all suites.
+	// They are used for suites with no entry in Suites.
+	RunID    int64            `json:"runID,omitempty"`
+	Failures map[string]int64 `json:"failures,omitempty"`
+}
+
+// getSuiteState returns the state for suite suitePath. Returns nil if there is none.
+func (f *regressionStateFile) getSuiteState(suitePath string) *regressionState {
+	if state, ok := f.Suites[suitePath]; ok {
+		return state
+	}
+	if f.RunID == 0 && len(f.Failures) == 0 {
+		return nil
+	}
+	return &regressionState{RunID: f.RunID, Failures: f.Failures}
+}
+
+// verifyRegressionInfo verifies previous run results can be found
+func verifyRegressionInfo(c *Options) error {
+	if c.ElasticInfo == nil && c.RegressionInfo.StateFile == "" {
+		return fmt.Errorf("regression detection requires either elastic or a state file")
+	}
+	return nil
+}
+
+// getRegressions compares failed tests in report with the ones failed in previous run
+func (i *Options) getRegressions(ctx context.Context, report *ginkgoTypes.Report) (*Regressions, error) {
+	var previous *regressionState
+	var err error
+	if i.ElasticInfo != nil {
+		previous, err = getPreviousStateFromElastic(ctx, i.getElasticInfo(), i.RunID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// An unreadable state file would otherwise disable regression detection for good:
+		// treat it as if there was no previous run, so state file is written again.
+		stateFile, err := readRegressionState(i.RegressionInfo.StateFile)
+		if err != nil {
+			utils.Byf(fmt.Sprintf("Ignoring regression state file. Run %d. Error: %v", i.RunID, err))
+		} else {
+			previous = stateFile.getSuiteState(report.SuitePath)
+		}
+	}
+
+	return compareWithPrevious(report, i.RunID, previous), nil
+}
+
+// saveRegressions writes failed tests of current run of the suite report belongs to, to
+// the state file. States of other suites are preserved. State file is used only when
+// elastic is not configured.
+func (i *Options) saveRegressions(report *ginkgoTypes.Report, regressions *Regressions) {
+	if i.ElasticInfo != nil || i.DryRun {
+		return
+	}
+
+	stateFile, err := readRegressionState(i.RegressionInfo.StateFile)
+	if err != nil {
+		// Unreadable state file is overwritten
+		stateFile = &regressionStateFile{}
+	}
+	if stateFile.Suites == nil {
+		stateFile.Suites = make(map[string]*regressionState)
+	}
+	stateFile.Suites[report.SuitePath] = regressions.getState(i.RunID)
+
+	if err := writeRegressionState(i.RegressionInfo.StateFile, stateFile); err != nil {
+		utils.Byf(fmt.Sprintf("Failed to write regression state file %s. Error: %v", i.RegressionInfo.StateFile, err))
+	}
+}
+
+// compareWithPrevious splits failed tests in report into new failures and still failing
+// tests and finds tests fixed since previous run. previous is nil if there is no previous run.
+func compareWithPrevious(report *ginkgoTypes.Report, runID int64, previous *regressionState) *Regressions {
+	if previous == nil {
+		previous = &regressionState{}
+	}
+
+	regressions := &Regressions{
+		PreviousRunID: previous.RunID,
+		NewFailures:   make([]RegressionTest, 0),
+		StillFailing:  make([]RegressionTest, 0),
+		Fixed:         make([]RegressionTest, 0),
+	}
+
+	failed := make(map[string]bool)
+	for i := range report.SpecReports {
+		if report.SpecReports[i].Failed() {
+			name, _ := ginkgo_helper.GetTestNameAndMaintainer(&report.SpecReports[i])
+			failed[name] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i := range report.SpecReports {
+		specReport := &report.SpecReports[i]
+		name, _ := ginkgo_helper.GetTestNameAndMaintainer(specReport)
+		if seen[name] {
+			continue
+		}
+
+		test := RegressionTest{Name: name, Text: getTestText(specReport)}
+		since, failedBefore := previous.Failures[name]
+		switch {
+		case failed[name] && failedBefore:
+			test.FailingSince = since
+			regressions.StillFailing = append(regressions.StillFailing, test)
+		case failed[name]:
+			test.FailingSince = runID
+			regressions.NewFailures = append(regressions.NewFailures, test)
+		case failedBefore && specReport.State == ginkgoTypes.SpecStatePassed:
+			regressions.Fixed = append(regressions.Fixed, test)
+		default:
+			continue
+		}
+		seen[name] = true
+	}
+
+	return regressions
+}
+
+// getState returns the regressionState for current run
+func (r *Regressions) getState(runID int64) *regressionState {
+	state := &regressionState{RunID: runID, Failures: make(map[string]int64)}
+	for i := range r.NewFailures {
+		state.Failures[r.NewFailures[i].Name] = r.NewFailures[i].FailingSince
+	}
+	for i := range r.StillFailing {
+		state.Failures[r.StillFailing[i].Name] = r.StillFailing[i].FailingSince
+	}
+	return state
+}
+
+// getPreviousStateFromElastic returns the failed tests of the last run, before runID,
+// stored in elastic. For each failed test, up to regressionHistoryRuns runs are searched
+// to find the run test has been failing since.
+// Returns nil if there is no previous run.
+func getPreviousStateFromElastic(ctx context.Context, info *elastic_helper.ElasticInfo,
+	runID int64) (*regressionState, error) {
+	runs, err := elastic_helper.GetPreviousRuns(ctx, info, runID, regressionHistoryRuns)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, nil
+	}
+
+	state := &regressionState{RunID: runs[0], Failures: make(map[string]int64)}
+
+	failures, err := elastic_helper.GetFailuresForRun(ctx, info, state.RunID)
+	if err != nil {
+		return nil, err
+	}
+	if len(failures) == 0 {
+		return state, nil
+	}
+
+	names := make([]string, len(failures))
+	for i := range failures {
+		names[i] = failures[i].Name
+	}
+
+	histories, err := elastic_helper.GetHistories(ctx, info, runs, names)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range histories {
+		if since, ok := histories[i].FirstFailingRun(); ok {
+			state.Failures[histories[i].Name] = since
+		}
+	}
+
+	return state, nil
+}
+
+// readRegressionState reads the state file. Returns an empty state file if file does not exist.
+func readRegressionState(path string) (*regressionStateFile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &regressionStateFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read regression state file %s: %w", path, err)
+	}
+
+	state := &regressionStateFile{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse regression state file %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// writeRegressionState writes state to the state file
+func writeRegressionState(path string, state *regressionStateFile) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// classifiedFailure is a failed test classified by regression detection
+type classifiedFailure struct {
+	specReport   *ginkgoTypes.SpecReport
+	failingSince int64 // run test has been failing since. Zero for new failures
+}
+
+// classifyFailures returns failed tests split into new failures and still failing tests.
+// A test which failed in more than one node is returned only once.
+func classifyFailures(report *ginkgoTypes.Report, regressions *Regressions) (newFailures, stillFailing []classifiedFailure) {
+	isNew := make(map[string]bool)
+	for i := range regressions.NewFailures {
+		isNew[regressions.NewFailures[i].Name] = true
+	}
+	since := make(map[string]int64)
+	for i := range regressions.StillFailing {
+		since[regressions.StillFailing[i].Name] = regressions.StillFailing[i].FailingSince
+	}
+
+	seen := make(map[string]bool)
+	for i := range report.SpecReports {
+		specReport := &report.SpecReports[i]
+		if !specReport.Failed() {
+			continue
+		}
+		name, _ := ginkgo_helper.GetTestNameAndMaintainer(specReport)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if failingSince, ok := since[name]; ok {
+			stillFailing = append(stillFailing, classifiedFailure{specReport: specReport, failingSince: failingSince})
+		} else if isNew[name] {
+			newFailures = append(newFailures, classifiedFailure{specReport: specReport})
+		}
+	}
+	return newFailures, stillFailing
+}
+
+// prepareRegressionMessage returns the markdown message listing new failures, still failing
+// tests (each with the run it has been failing since) and fixed tests, followed by flaky tests.
+// Failed tests are listed with the corresponding jira issue if any and, if mention is not nil,
+// their owner mentions.
+func prepareRegressionMessage(report *ginkgoTypes.Report, c *Options, openIssues []jira.Issue,
+	regressions *Regressions, mention mentionFunc) string {
+	newFailures, stillFailing := classifyFailures(report, regressions)
+
+	newMsg := ""
+	for _, failure := range newFailures {
+		newMsg += fmt.Sprintf("Test: %q failed in run %d ", getTestText(failure.specReport), c.RunID)
+		newMsg += getIssueText(openIssues, failure.specReport) + getOwnerText(c, failure.specReport, mention) + "  \n"
+	}
+	stillMsg := ""
+	for _, failure := range stillFailing {
+		stillMsg += fmt.Sprintf("Test: %q failing since run %d ", getTestText(failure.specReport), failure.failingSince)
+		stillMsg += getIssueText(openIssues, failure.specReport) + getOwnerText(c, failure.specReport, mention) + "  \n"
+	}
+
+	msg := ""
+	if newMsg != "" {
+		msg += "**New failures**  \n" + newMsg
+	}
+	if stillMsg != "" {
+		msg += "**Still failing**  \n" + stillMsg
+	}
+	if len(regressions.Fixed) != 0 {
+		msg += "**Fixed since last run**  \n"
+		for i := range regressions.Fixed {
+			msg += fmt.Sprintf("Test: %q fixed in run %d  \n", regressions.Fixed[i].Text, c.RunID)
+		}
+	}
+
+	return msg + getFlakyMessage(report, c)
+}
@@ -0,0 +1,202 @@
+package process_result_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/process_result"
+)
+
+// regressionSink is a Sink recording regressions of last processed run, also per suite path.
+type regressionSink struct {
+	regressions *process_result.Regressions
+	suites      map[string]*process_result.Regressions
+}
+
+func (s *regressionSink) Verify(ctx context.Context) error {
+	return nil
+}
+
+func (s *regressionSink) Process(ctx context.Context, report *ginkgoTypes.Report, runInfo *process_result.RunInfo) error {
+	s.regressions = runInfo.Regressions
+	if s.suites == nil {
+		s.suites = make(map[string]*process_result.Regressions)
+	}
+	s.suites[report.SuitePath] = runInfo.Regressions
+	return nil
+}
+
+// getRegressionReport returns a report where tests listed in failed failed and all others passed
+func getRegressionReport(failed ...string) *ginkgoTypes.Report {
+	isFailed := make(map[string]bool)
+	for i := range failed {
+		isFailed[failed[i]] = true
+	}
+
+	report := &ginkgoTypes.Report{}
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		state := ginkgoTypes.SpecStatePassed
+		if isFailed[name] {
+			state = ginkgoTypes.SpecStateFailed
+		}
+		report.SpecReports = append(report.SpecReports, ginkgoTypes.SpecReport{
+			LeafNodeType: ginkgoTypes.NodeTypeIt,
+			LeafNodeText: name,
+			State:        state,
+			NumAttempts:  1,
+		})
+	}
+	return report
+}
+
+func getTexts(tests []process_result.RegressionTest) []string {
+	texts := make([]string, len(tests))
+	for i := range tests {
+		texts[i] = tests[i].Text
+	}
+	return texts
+}
+
+var _ = Describe("Regressions", func() {
+	var stateFile string
+	var sink *regressionSink
+
+	BeforeEach(func() {
+		stateFile = filepath.Join(GinkgoT().TempDir(), "state.json")
+		sink = &regressionSink{}
+	})
+
+	processRun := func(runID int64, report *ginkgoTypes.Report, setters ...process_result.Option) {
+		setters = append(setters,
+			process_result.WithRunID(runID),
+			process_result.WithRegressionDetection(process_result.RegressionInfo{StateFile: stateFile}),
+			process_result.WithSink(sink),
+			process_result.WithLogWriter(GinkgoWriter),
+		)
+		Expect(process_result.ProcessReport(context.TODO(), report, setters...)).To(Succeed())
+	}
+
+	It("requires elastic or a state file", func() {
+		err := process_result.ProcessReport(context.TODO(), getRegressionReport(),
+			process_result.WithRegressionDetection(process_result.RegressionInfo{}))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("splits failures into new, still failing and fixed", func() {
+		processRun(1, getRegressionReport("alpha", "beta"))
+		Expect(sink.regressions.PreviousRunID).To(BeZero())
+		Expect(getTexts(sink.regressions.NewFailures)).To(Equal([]string{"alpha", "beta"}))
+		Expect(sink.regressions.NewFailures[0].FailingSince).To(BeEquivalentTo(1))
+		Expect(sink.regressions.Changed()).To(BeTrue())
+
+		processRun(2, getRegressionReport("alpha", "gamma"))
+		Expect(sink.regressions.PreviousRunID).To(BeEquivalentTo(1))
+		Expect(getTexts(sink.regressions.NewFailures)).To(Equal([]string{"gamma"}))
+		Expect(getTexts(sink.regressions.StillFailing)).To(Equal([]string{"alpha"}))
+		Expect(sink.regressions.StillFailing[0].FailingSince).To(BeEquivalentTo(1))
+		Expect(getTexts(sink.regressions.Fixed)).To(Equal([]string{"beta"}))
+
+		processRun(3, getRegressionReport("alpha", "gamma"))
+		Expect(sink.regressions.NewFailures).To(BeEmpty())
+		Expect(getTexts(sink.regressions.StillFailing)).To(Equal([]string{"alpha", "gamma"}))
+		Expect(sink.regressions.StillFailing[1].FailingSince).To(BeEquivalentTo(2))
+		Expect(sink.regressions.Fixed).To(BeEmpty())
+		Expect(sink.regressions.Changed()).To(BeFalse())
+	})
+
+	It("recovers from an unreadable state file", func() {
+		Expect(os.WriteFile(stateFile, []byte("{corrupted"), 0600)).To(Succeed())
+		processRun(4, getRegressionReport("alpha"))
+		Expect(sink.regressions).ToNot(BeNil())
+		Expect(sink.regressions.PreviousRunID).To(BeZero())
+		Expect(getTexts(sink.regressions.NewFailures)).To(Equal([]string{"alpha"}))
+
+		processRun(5, getRegressionReport("alpha"))
+		Expect(sink.regressions.PreviousRunID).To(BeEquivalentTo(4))
+		Expect(getTexts(sink.regressions.StillFailing)).To(Equal([]string{"alpha"}))
+	})
+
+	It("keeps state of every suite processed in the same run", func() {
+		processRuns := func(runID int64) {
+			suiteA := getRegressionReport("alpha")
+			suiteA.SuitePath = "/e2e/a"
+			suiteB := getRegressionReport("beta")
+			suiteB.SuitePath = "/e2e/b"
+			Expect(process_result.ProcessReports(context.TODO(), []ginkgoTypes.Report{*suiteA, *suiteB},
+				process_result.WithRunID(runID),
+				process_result.WithRegressionDetection(process_result.RegressionInfo{StateFile: stateFile}),
+				process_result.WithSink(sink),
+				process_result.WithLogWriter(GinkgoWriter),
+			)).To(Succeed())
+		}
+
+		processRuns(1)
+		Expect(getTexts(sink.suites["/e2e/a"].NewFailures)).To(Equal([]string{"alpha"}))
+		Expect(getTexts(sink.suites["/e2e/b"].NewFailures)).To(Equal([]string{"beta"}))
+
+		processRuns(2)
+		for _, suite := range []string{"/e2e/a", "/e2e/b"} {
+			Expect(sink.suites[suite].PreviousRunID).To(BeEquivalentTo(1))
+			Expect(sink.suites[suite].NewFailures).To(BeEmpty())
+			Expect(sink.suites[suite].StillFailing).To(HaveLen(1))
+			Expect(sink.suites[suite].StillFailing[0].FailingSince).To(BeEquivalentTo(1))
+		}
+	})
+
+	It("reads state file written for a single suite", func() {
+		Expect(os.WriteFile(stateFile, []byte(`{"runID": 3, "failures": {"alpha": 2}}`), 0600)).To(Succeed())
+		processRun(4, getRegressionReport("alpha"))
+		Expect(sink.regressions.PreviousRunID).To(BeEquivalentTo(3))
+		Expect(getTexts(sink.regressions.StillFailing)).To(Equal([]string{"alpha"}))
+		Expect(sink.regressions.StillFailing[0].FailingSince).To(BeEquivalentTo(2))
+	})
+
+	It("does not write state file on dry run", func() {
+		processRun(1, getRegressionReport("alpha"), process_result.WithDryRun())
+		Expect(sink.regressions.NewFailures).To(HaveLen(1))
+		_, err := os.Stat(stateFile)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("PrepareRegressionMessage lists new, still failing and fixed tests", func() {
+		c := &process_result.Options{}
+		process_result.WithRunID(12)(c)
+
+		regressions := &process_result.Regressions{
+			PreviousRunID: 11,
+			NewFailures:   []process_result.RegressionTest{{Name: "gamma", Text: "gamma", FailingSince: 12}},
+			StillFailing:  []process_result.RegressionTest{{Name: "alpha", Text: "alpha", FailingSince: 7}},
+			Fixed:         []process_result.RegressionTest{{Name: "beta", Text: "beta"}},
+		}
+		message := process_result.PrepareRegressionMessage(getRegressionReport("alpha", "gamma"), c, nil, regressions, nil)
+		Expect(message).To(ContainSubstring("**New failures**  \nTest: \"gamma\" failed in run 12"))
+		Expect(message).To(ContainSubstring("**Still failing**  \nTest: \"alpha\" failing since run 7"))
+		Expect(message).To(ContainSubstring("**Fixed since last run**  \nTest: \"beta\" fixed in run 12"))
+	})
+
+	It("ShouldNotify skips notifications only when failures did not change", func() {
+		c := &process_result.Options{}
+		process_result.WithRegressionDetection(process_result.RegressionInfo{NotifyOnlyOnChange: true})(c)
+
+		unchanged := &process_result.RunInfo{Regressions: &process_result.Regressions{
+			StillFailing: []process_result.RegressionTest{{Name: "alpha"}},
+		}}
+		Expect(process_result.ShouldNotify(c, unchanged)).To(BeFalse())
+
+		changed := &process_result.RunInfo{Regressions: &process_result.Regressions{
+			Fixed: []process_result.RegressionTest{{Name: "alpha"}},
+		}}
+		Expect(process_result.ShouldNotify(c, changed)).To(BeTrue())
+
+		// Regressions could not be computed
+		Expect(process_result.ShouldNotify(c, &process_result.RunInfo{})).To(BeTrue())
+
+		c.RegressionInfo.NotifyOnlyOnChange = false
+		Expect(process_result.ShouldNotify(c, unchanged)).To(BeTrue())
+	})
+})
@@ -0,0 +1,72 @@
+package process_result
+
+import (
+	"context"
+
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/elastic_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/jira_helper"
+)
+
+// getPassingSince returns, for each test which passed in current run and in the
+// JiraInfo.ResolveAfterPasses - 1 previous runs, the first of those runs.
+// Previous runs results are read from elastic.
+func getPassingSince(ctx context.Context, report *ginkgoTypes.Report, runID int64,
+	c *Options) (map[string]int64, error) {
+	passed := make([]string, 0)
+	for i := range report.SpecReports {
+		if jira_helper.IsPassed(&report.SpecReports[i]) {
+			testName, _ := ginkgo_helper.GetTestNameAndMaintainer(&report.SpecReports[i])
+			passed = append(passed, testName)
+		}
+	}
+
+	previousRuns := c.JiraInfo.ResolveAfterPasses - 1
+	if previousRuns == 0 || len(passed) == 0 {
+		return countPassingSince(runID, passed, nil, nil, previousRuns), nil
+	}
+
+	runs, err := elastic_helper.GetPreviousRuns(ctx, c.getElasticInfo(), runID, previousRuns)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) < previousRuns {
+		return map[string]int64{}, nil
+	}
+
+	histories, err := elastic_helper.GetHistories(ctx, c.getElasticInfo(), runs, passed)
+	if err != nil {
+		return nil, err
+	}
+
+	return countPassingSince(runID, passed, runs, histories, previousRuns), nil
+}
+
+// countPassingSince returns, for each test in passed which also passed at first attempt in
+// all previousRuns runs (listed most recent first), the oldest of those runs. histories
+// contains the history of tests across those runs. If previousRuns is zero, current run
+// is returned for each test in passed.
+func countPassingSince(runID int64, passed []string, runs []int64, histories []*elastic_helper.TestHistory,
+	previousRuns int) map[string]int64 {
+	passingSince := make(map[string]int64)
+	if previousRuns == 0 {
+		for i := range passed {
+			passingSince[passed[i]] = runID
+		}
+		return passingSince
+	}
+
+	passes := make(map[string]int)
+	for i := range histories {
+		passes[histories[i].Name] = histories[i].PassStreak()
+	}
+
+	for i := range passed {
+		if passes[passed[i]] >= previousRuns {
+			passingSince[passed[i]] = runs[len(runs)-1]
+		}
+	}
+	return passingSince
+}
@@ -0,0 +1,35 @@
+package process_result_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/elastic_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/process_result"
+)
+
+var _ = Describe("Resolve jira issues", func() {
+	It("countPassingSince returns current run when a single pass is required", func() {
+		passingSince := process_result.CountPassingSince(10, []string{"a", "b"}, nil, nil, 0)
+		Expect(passingSince).To(Equal(map[string]int64{"a": 10, "b": 10}))
+	})
+
+	It("countPassingSince returns tests which passed in all previous runs", func() {
+		histories := []*elastic_helper.TestHistory{
+			{Name: "a", Results: []elastic_helper.ElasticResult{{Run: 9, Result: "passed"}, {Run: 8, Result: "passed"}}},
+			{Name: "b", Results: []elastic_helper.ElasticResult{{Run: 9, Result: "passed"}, {Run: 8, Result: "failed"}}},
+			{Name: "c", Results: []elastic_helper.ElasticResult{{Run: 9, Result: "passed", Flaky: true}, {Run: 8, Result: "passed"}}},
+			{Name: "e", Results: []elastic_helper.ElasticResult{{Run: 9, Result: "passed"}}},
+		}
+		passingSince := process_result.CountPassingSince(10, []string{"a", "b", "c", "d", "e"}, []int64{9, 8}, histories, 2)
+		Expect(passingSince).To(Equal(map[string]int64{"a": 8}))
+	})
+
+	It("requires elastic when more than one passing run is required", func() {
+		c := &process_result.Options{}
+		process_result.WithJira(process_result.JiraInfo{BaseURL: "https://jira.org", ResolveAfterPasses: 3})(c)
+		Expect(process_result.VerifyJiraInfo(context.TODO(), c)).To(MatchError(ContainSubstring("requires elastic")))
+	})
+})
@@ -0,0 +1,241 @@
+package process_result
+
+import (
+	"context"
+	"fmt"
+
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+
+	"github.com/andygrunwald/go-jira"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/elastic_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/jira_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/slack_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/webex_helper"
+)
+
+// Sink is a destination test-suite results are sent to.
+// Elastic, Jira, Webex and Slack are built-in sinks, registered with
+// WithElastic, WithJira, WithWebex and WithSlack respectively.
+// Any other destination can be registered with WithSink.
+type Sink interface {
+	// Verify verifies the sink configuration. It is invoked by Register
+	// before the test-suite runs.
+	Verify(ctx context.Context) error
+
+	// Process processes the test-suite report. It is invoked once the
+	// test-suite completes.
+	Process(ctx context.Context, report *ginkgoTypes.Report, runInfo *RunInfo) error
+}
+
+// RunInfo contains information about the run being processed.
+// The same instance is passed to every sink, in registration order.
+type RunInfo struct {
+	// RunID is the run id
+	RunID int64
+
+	// DryRun indicates if this is a dryRun. When set, sinks must not modify
+	// any external system.
+	DryRun bool
+
+	// OpenIssues contains the open Jira issues filed for failed tests.
+	// It is set by the Jira sink (if registered), which always runs before
+	// Webex and Slack sinks.
+	OpenIssues []jira.Issue
+
+	// Regressions contains failed tests compared with previous run.
+	// It is set only when regression detection is enabled and previous run
+	// results could be read.
+	Regressions *Regressions
+}
+
+// WithSink registers a custom sink. Custom sinks are processed after the
+// built-in ones, in the order they are registered.
+func WithSink(sink Sink) Option {
+	return func(args *Options) {
+		args.Sinks = append(args.Sinks, sink)
+	}
+}
+
+// getSinks returns all sinks: built-in ones first, followed by custom ones.
+func (i *Options) getSinks() []Sink {
+	sinks := make([]Sink, 0)
+
+	if i.ElasticInfo != nil {
+		sinks = append(sinks, &elasticSink{c: i})
+	}
+
+	if i.JiraInfo != nil {
+		sinks = append(sinks, &jiraSink{c: i})
+	}
+
+	if i.WebexInfo != nil {
+		sinks = append(sinks, &webexSink{c: i})
+	}
+
+	if i.SlackInfo != nil {
+		sinks = append(sinks, &slackSink{c: i})
+	}
+
+	return append(sinks, i.Sinks...)
+}
+
+// runSinks invokes every sink on report. A failing sink does not prevent
+// following sinks from running.
+// Returns a *ProcessError listing failed sinks, if any.
+func runSinks(ctx context.Context, report *ginkgoTypes.Report, sinks []Sink, runInfo *RunInfo) *ProcessError {
+	var processErr *ProcessError
+	for i := range sinks {
+		if err := sinks[i].Process(ctx, report, runInfo); err != nil {
+			name := getSinkName(sinks[i])
+			utils.Byf(fmt.Sprintf("Sink %s failed to process results. Run %d. Error: %v", name, runInfo.RunID, err))
+			if processErr == nil {
+				processErr = &ProcessError{RunID: runInfo.RunID}
+			}
+			processErr.Errors = append(processErr.Errors, &SinkError{Sink: name, Index: i, Err: err})
+		}
+	}
+
+	return processErr
+}
+
+// elasticSink stores test results in an elastic DB.
+type elasticSink struct {
+	c *Options
+}
+
+func (s *elasticSink) Name() string {
+	return "elastic"
+}
+
+func (s *elasticSink) Verify(ctx context.Context) error {
+	return verifyElasticInfo(ctx, s.c)
+}
+
+func (s *elasticSink) Process(ctx context.Context, report *ginkgoTypes.Report, runInfo *RunInfo) error {
+	utils.Byf(fmt.Sprintf("Save results to elastic db. Run %d", runInfo.RunID))
+	return elastic_helper.StoreResults(report, runInfo.RunID, s.c.getElasticInfo(), runInfo.DryRun)
+}
+
+// jiraSink files a Jira issue for each failed test.
+type jiraSink struct {
+	c *Options
+}
+
+func (s *jiraSink) Name() string {
+	return "jira"
+}
+
+func (s *jiraSink) Verify(ctx context.Context) error {
+	return verifyJiraInfo(ctx, s.c)
+}
+
+func (s *jiraSink) Process(ctx context.Context, report *ginkgoTypes.Report, runInfo *RunInfo) error {
+	utils.Byf(fmt.Sprintf("File jira issue for failed tests. Run %d", runInfo.RunID))
+	errs := make([]error, 0)
+	if err := jira_helper.FileJiraIssuesForFailedTests(ctx, report, runInfo.RunID, s.c.getJiraInfo()); err != nil {
+		errs = append(errs, err)
+	}
+
+	if s.c.JiraInfo.ResolveAfterPasses > 0 {
+		if err := s.resolveIssues(ctx, report, runInfo.RunID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	openIssues, err := jira_helper.GetOpenE2EJiraIssue(ctx, s.c.getJiraInfo())
+	if err != nil {
+		errs = append(errs, err)
+	}
+	runInfo.OpenIssues = openIssues
+
+	return utils.AggregateErrors(errs)
+}
+
+// resolveIssues resolves issues of tests which passed in the last ResolveAfterPasses runs
+func (s *jiraSink) resolveIssues(ctx context.Context, report *ginkgoTypes.Report, runID int64) error {
+	utils.Byf(fmt.Sprintf("Resolve jira issues for passed tests. Run %d", runID))
+	passingSince, err := getPassingSince(ctx, report, runID, s.c)
+	if err != nil {
+		return err
+	}
+	return jira_helper.ResolveIssuesForPassedTests(ctx, report, passingSince, s.c.getJiraInfo())
+}
+
+// webexSink sends a notification for failed tests to a Webex room.
+type webexSink struct {
+	c *Options
+}
+
+func (s *webexSink) Name() string {
+	return "webex"
+}
+
+func (s *webexSink) Verify(ctx context.Context) error {
+	return verifyWebexInfo(ctx, s.c)
+}
+
+func (s *webexSink) Process(ctx context.Context, report *ginkgoTypes.Report, runInfo *RunInfo) error {
+	if !shouldNotify(s.c, runInfo) {
+		return nil
+	}
+	msg := getMessage(report, s.c, runInfo, webexMentions)
+	if msg == "" {
+		// Webex rejects messages with no text
+		utils.Byf("No failed or flaky tests. No webex notification to send")
+		return nil
+	}
+	utils.Byf(fmt.Sprintf("Send failed tests notification to webex room %s", s.c.WebexInfo.Room))
+	return sendWebexNotification(report, s.c, msg)
+}
+
+// slackSink sends a notification for failed tests to a Slack channel.
+type slackSink struct {
+	c *Options
+}
+
+func (s *slackSink) Name() string {
+	return "slack"
+}
+
+func (s *slackSink) Verify(ctx context.Context) error {
+	return verifySlackInfo(ctx, s.c)
+}
+
+func (s *slackSink) Process(ctx context.Context, report *ginkgoTypes.Report, runInfo *RunInfo) error {
+	if !shouldNotify(s.c, runInfo) {
+		return nil
+	}
+	utils.Byf(fmt.Sprintf("Send failed tests notification to slack %s", slack_helper.GetDestination(s.c.getSlackInfo())))
+	msg := prepareSlackMessage(report, s.c, runInfo)
+	return sendSlackNotification(report, s.c, msg)
+}
+
+// shouldNotify returns false if notifications must be sent only when failed tests change
+// and they did not change since previous run.
+func shouldNotify(c *Options, runInfo *RunInfo) bool {
+	if c.RegressionInfo == nil || !c.RegressionInfo.NotifyOnlyOnChange || runInfo.Regressions == nil {
+		return true
+	}
+	if !runInfo.Regressions.Changed() {
+		utils.Byf(fmt.Sprintf("No new failures or fixed tests since run %d. Skip notification",
+			runInfo.Regressions.PreviousRunID))
+		return false
+	}
+	return true
+}
+
+// sendWebexNotification send a message for each failed test.
+func sendWebexNotification(report *ginkgoTypes.Report, c *Options, msg string) error {
+	utils.Byf("Eventually sending Webex notifications")
+
+	return webex_helper.SendWebexMessage(c.getWebexInfo(), msg)
+}
+
+// sendSlackNotification send a message, as Block Kit blocks, for failed tests.
+func sendSlackNotification(report *ginkgoTypes.Report, c *Options, msg *slack_helper.Message) error {
+	utils.Byf("Eventually sending Slack notifications")
+
+	return slack_helper.SendSlackMessage(c.getSlackInfo(), msg)
+}
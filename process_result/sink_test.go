@@ -0,0 +1,187 @@
+package process_result_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/process_result"
+)
+
+// fakeSink is a Sink recording Process invocations.
+type fakeSink struct {
+	name      string
+	err       error
+	verified  int
+	processed []int64
+}
+
+func (s *fakeSink) Name() string {
+	return s.name
+}
+
+func (s *fakeSink) Verify(ctx context.Context) error {
+	s.verified++
+	return nil
+}
+
+func (s *fakeSink) Process(ctx context.Context, report *ginkgoTypes.Report, runInfo *process_result.RunInfo) error {
+	s.processed = append(s.processed, runInfo.RunID)
+	return s.err
+}
+
+var _ = Describe("Sinks", func() {
+	It("WithSink registers a custom sink", func() {
+		sink := &fakeSink{}
+		c := &process_result.Options{}
+		process_result.WithSink(sink)(c)
+		Expect(c.Sinks).To(HaveLen(1))
+		Expect(c.GetSinks()).To(HaveLen(1))
+		Expect(c.GetSinks()[0]).To(Equal(sink))
+	})
+
+	It("getSinks returns built-in sinks before custom ones", func() {
+		sink := &fakeSink{}
+		c := &process_result.Options{
+			ElasticInfo: getElasticInfo(),
+			JiraInfo:    getJiraInfo(),
+			SlackInfo:   getSlackInfo(),
+			WebexInfo:   getWebexInfo(),
+		}
+		process_result.WithSink(sink)(c)
+		sinks := c.GetSinks()
+		Expect(sinks).To(HaveLen(5))
+		Expect(sinks[4]).To(Equal(sink))
+	})
+
+	It("webex sink sends nothing when no test failed", func() {
+		c := &process_result.Options{WebexInfo: getWebexInfo(), RunID: 12}
+		sinks := c.GetSinks()
+		Expect(sinks).To(HaveLen(1))
+
+		report := &ginkgoTypes.Report{SpecReports: ginkgoTypes.SpecReports{
+			{LeafNodeType: ginkgoTypes.NodeTypeIt, LeafNodeText: "passes", State: ginkgoTypes.SpecStatePassed, NumAttempts: 1},
+		}}
+		Expect(sinks[0].Process(context.TODO(), report, &process_result.RunInfo{RunID: 12})).To(Succeed())
+	})
+
+	It("getSinks returns no sink when nothing is configured", func() {
+		c := &process_result.Options{}
+		Expect(c.GetSinks()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Sink errors", func() {
+	It("runSinks runs all sinks and aggregates errors", func() {
+		runID := int64(3456)
+		sinkErr := errors.New("destination unreachable")
+		failing := &fakeSink{name: "failing", err: sinkErr}
+		succeeding := &fakeSink{name: "succeeding"}
+		report := &ginkgoTypes.Report{SpecReports: getSpecReport()}
+
+		processErr := process_result.RunSinks(context.TODO(), report,
+			[]process_result.Sink{failing, succeeding}, &process_result.RunInfo{RunID: runID})
+		Expect(processErr).ToNot(BeNil())
+		Expect(processErr.RunID).To(Equal(runID))
+		Expect(processErr.Errors).To(HaveLen(1))
+		Expect(processErr.Errors[0].Sink).To(Equal("failing"))
+		Expect(errors.Is(processErr.Errors[0], sinkErr)).To(BeTrue())
+		Expect(processErr.Error()).To(ContainSubstring("destination unreachable"))
+		Expect(succeeding.processed).To(Equal([]int64{runID}))
+	})
+
+	It("runSinks errors match the errors returned by sinks", func() {
+		timeoutErr := errors.New("timeout")
+		unreachableErr := &os.PathError{Op: "open", Path: "/unreachable", Err: os.ErrNotExist}
+		failing := &fakeSink{name: "failing", err: utils.AggregateErrors([]error{timeoutErr, unreachableErr})}
+		report := &ginkgoTypes.Report{SpecReports: getSpecReport()}
+
+		var err error = process_result.RunSinks(context.TODO(), report, []process_result.Sink{failing},
+			&process_result.RunInfo{})
+		Expect(errors.Is(err, timeoutErr)).To(BeTrue())
+		Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		var pathErr *os.PathError
+		Expect(errors.As(err, &pathErr)).To(BeTrue())
+		Expect(pathErr.Path).To(Equal("/unreachable"))
+		var sinkErr *process_result.SinkError
+		Expect(errors.As(err, &sinkErr)).To(BeTrue())
+		Expect(sinkErr.Sink).To(Equal("failing"))
+	})
+
+	It("runSinks returns nil when all sinks succeed", func() {
+		report := &ginkgoTypes.Report{SpecReports: getSpecReport()}
+		processErr := process_result.RunSinks(context.TODO(), report,
+			[]process_result.Sink{&fakeSink{name: "a"}, &fakeSink{name: "b"}}, &process_result.RunInfo{})
+		Expect(processErr).To(BeNil())
+	})
+
+	It("writeStatusFile reports outcome of each sink", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "status.json")
+		sinks := []process_result.Sink{&fakeSink{name: "failing"}, &fakeSink{name: "succeeding"}}
+		processErr := &process_result.ProcessError{
+			RunID:  10,
+			Errors: []*process_result.SinkError{{Sink: "failing", Err: errors.New("timeout")}},
+		}
+		Expect(process_result.WriteStatusFile(path, 10, sinks, processErr)).To(Succeed())
+
+		data, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		status := map[string]interface{}{}
+		Expect(json.Unmarshal(data, &status)).To(Succeed())
+		Expect(status["runID"]).To(BeEquivalentTo(10))
+		Expect(status["succeeded"]).To(BeFalse())
+		Expect(status["sinks"]).To(ConsistOf(
+			map[string]interface{}{"name": "failing", "succeeded": false, "error": "timeout"},
+			map[string]interface{}{"name": "succeeding", "succeeded": true},
+		))
+	})
+
+	It("writeStatusFile reports outcome of sinks sharing a name", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "status.json")
+		sinks := []process_result.Sink{&fakeSink{name: "webhook"}, &fakeSink{name: "webhook", err: errors.New("timeout")}}
+		processErr := process_result.RunSinks(context.TODO(), &ginkgoTypes.Report{}, sinks, &process_result.RunInfo{RunID: 10})
+		Expect(process_result.WriteStatusFile(path, 10, sinks, processErr)).To(Succeed())
+
+		data, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		status := map[string]interface{}{}
+		Expect(json.Unmarshal(data, &status)).To(Succeed())
+		Expect(status["sinks"]).To(Equal([]interface{}{
+			map[string]interface{}{"name": "webhook", "succeeded": true},
+			map[string]interface{}{"name": "webhook", "succeeded": false, "error": "timeout"},
+		}))
+	})
+})
+
+var _ = Describe("ProcessReports", func() {
+	It("verifies sinks once and processes every report as part of the same run", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "status.json")
+		failing := &fakeSink{name: "failing", err: errors.New("timeout")}
+		succeeding := &fakeSink{name: "succeeding"}
+		reports := []ginkgoTypes.Report{{SuitePath: "/src/e2e"}, {SuitePath: "/src/upgrade"}}
+
+		err := process_result.ProcessReports(context.TODO(), reports, process_result.WithRunID(12),
+			process_result.WithSink(failing), process_result.WithSink(succeeding), process_result.WithStatusFile(path))
+		Expect(err).To(MatchError(
+			"failed to process results for run 12: sink failing: timeout; sink failing: timeout"))
+		Expect(failing.verified).To(Equal(1))
+		Expect(succeeding.verified).To(Equal(1))
+		Expect(succeeding.processed).To(Equal([]int64{12, 12}))
+
+		data, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		status := map[string]interface{}{}
+		Expect(json.Unmarshal(data, &status)).To(Succeed())
+		Expect(status["sinks"]).To(Equal([]interface{}{
+			map[string]interface{}{"name": "failing", "succeeded": false, "error": "timeout; timeout"},
+			map[string]interface{}{"name": "succeeding", "succeeded": true},
+		}))
+	})
+})
@@ -0,0 +1,297 @@
+package process_result
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/jira_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/slack_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/utils"
+)
+
+// prepareSlackMessage returns the Block Kit message for a run:
+// - a header with suite name, run id and pass/fail counts;
+// - a section per failed test, with failure location, jira issue (if any) and owner mentions.
+// When regression detection is enabled, failed tests are grouped into new failures and still
+// failing tests, followed by fixed tests;
+// - a section listing flaky tests;
+// - a context with run duration.
+// With SlackInfo.Threaded, see prepareSlackThread.
+// With SlackInfo.UploadFailureLog, the failure log (see getSlackFailureLog) is uploaded and linked.
+func prepareSlackMessage(report *ginkgoTypes.Report, c *Options, runInfo *RunInfo) *slack_helper.Message {
+	if c.SlackInfo != nil && c.SlackInfo.Threaded {
+		return prepareSlackThread(report, c, runInfo)
+	}
+
+	header := getSummaryHeader(report, c)
+	msg := &slack_helper.Message{
+		Header:  header,
+		Context: fmt.Sprintf("Duration: %s", report.RunTime.Round(time.Second)),
+		Text:    header,
+	}
+
+	if runInfo.Regressions != nil {
+		msg.Sections = getSlackRegressionSections(report, c, runInfo.OpenIssues, runInfo.Regressions)
+	} else {
+		for i := range report.SpecReports {
+			specReport := &report.SpecReports[i]
+			if specReport.Failed() {
+				msg.Sections = append(msg.Sections,
+					getSlackFailureSection(c, specReport, runInfo.OpenIssues, fmt.Sprintf("failed in run %d", c.RunID)))
+			}
+		}
+	}
+
+	if flaky := getSlackFlakySection(report, c); flaky != "" {
+		msg.Sections = append(msg.Sections, flaky)
+	}
+
+	if len(msg.Sections) == 0 {
+		msg.Sections = append(msg.Sections, ":white_check_mark: No failed tests")
+	}
+
+	msg.File = getSlackFailureLog(report, c)
+	return msg
+}
+
+// getSummaryHeader returns suite name, run id and the number of failed, passed,
+// flaky and skipped tests
+func getSummaryHeader(report *ginkgoTypes.Report, c *Options) string {
+	var failed, passed, flaky, skipped int
+	for i := range report.SpecReports {
+		specReport := &report.SpecReports[i]
+		switch {
+		case specReport.Failed():
+			failed++
+		case specReport.LeafNodeType != ginkgoTypes.NodeTypeIt:
+			continue
+		case ginkgo_helper.IsFlaky(specReport):
+			flaky++
+		case specReport.State == ginkgoTypes.SpecStatePassed:
+			passed++
+		case specReport.State == ginkgoTypes.SpecStateSkipped || specReport.State == ginkgoTypes.SpecStatePending:
+			skipped++
+		}
+	}
+
+	header := fmt.Sprintf("Run %d", c.RunID)
+	if report.SuiteDescription != "" {
+		header = fmt.Sprintf("%s run %d", report.SuiteDescription, c.RunID)
+	}
+	header = fmt.Sprintf("%s: %d failed, %d passed", header, failed, passed)
+	if flaky != 0 {
+		header += fmt.Sprintf(", %d flaky", flaky)
+	}
+	if skipped != 0 {
+		header += fmt.Sprintf(", %d skipped", skipped)
+	}
+	return header
+}
+
+// getSlackFailureSection returns the mrkdwn section of a failed test: test text followed by
+// status, failure location, link to jira issue (if any) and owner mentions.
+func getSlackFailureSection(c *Options, specReport *ginkgoTypes.SpecReport, openIssues []jira.Issue,
+	status string) string {
+	section := fmt.Sprintf(":x: *%s* %s\n`%s`", slack_helper.Escape(getTestText(specReport)), status,
+		slack_helper.Escape(specReport.Failure.Location.String()))
+	if openIssue := jira_helper.FindExistingIssue(openIssues, specReport); openIssue != nil {
+		section += "\nJira: " + getSlackIssueLink(c, openIssue.Key)
+	}
+	if owners := getOwnerText(c, specReport, slackMentions); owners != "" {
+		section += "\n" + strings.TrimSpace(owners)
+	}
+	return section
+}
+
+// getSlackIssueLink returns the mrkdwn link to jira issue key
+func getSlackIssueLink(c *Options, key string) string {
+	if c.JiraInfo == nil || c.JiraInfo.BaseURL == "" {
+		return key
+	}
+	return fmt.Sprintf("<%s/browse/%s|%s>", strings.TrimSuffix(c.JiraInfo.BaseURL, "/"), key, key)
+}
+
+// getSlackRegressionSections returns the sections of new failures, still failing tests
+// and fixed tests. Each group starts with a title section.
+func getSlackRegressionSections(report *ginkgoTypes.Report, c *Options, openIssues []jira.Issue,
+	regressions *Regressions) []string {
+	sections := make([]string, 0)
+	newFailures, stillFailing := classifyFailures(report, regressions)
+
+	if len(newFailures) != 0 {
+		sections = append(sections, "*New failures*")
+		for _, failure := range newFailures {
+			sections = append(sections, getSlackFailureSection(c, failure.specReport, openIssues,
+				fmt.Sprintf("failed in run %d", c.RunID)))
+		}
+	}
+
+	if len(stillFailing) != 0 {
+		sections = append(sections, "*Still failing*")
+		for _, failure := range stillFailing {
+			sections = append(sections, getSlackFailureSection(c, failure.specReport, openIssues,
+				fmt.Sprintf("failing since run %d", failure.failingSince)))
+		}
+	}
+
+	if fixed := getSlackFixedSection(regressions); fixed != "" {
+		sections = append(sections, fixed)
+	}
+
+	return sections
+}
+
+// getSlackFixedSection returns the mrkdwn section listing fixed tests. Empty if there are none.
+func getSlackFixedSection(regressions *Regressions) string {
+	if len(regressions.Fixed) == 0 {
+		return ""
+	}
+
+	fixed := "*Fixed since last run*"
+	for i := range regressions.Fixed {
+		fixed += fmt.Sprintf("\n:white_check_mark: %s", slack_helper.Escape(regressions.Fixed[i].Text))
+	}
+	return fixed
+}
+
+// maxSlackDetailLength is the maximum length of failure message, stack trace and output
+// in thread replies. Each, once escaped, usually fits a single section.
+const maxSlackDetailLength = 2500
+
+// prepareSlackThread returns a short summary message (header, number of failed tests and context)
+// whose replies contain the details of each failed test: failure section (see getSlackFailureSection),
+// failure message, stack trace and GinkgoWriter output. When regression detection is enabled,
+// replies of new failures come first and, with SlackInfo.BroadcastNewFailures, are also sent
+// to the channel. Fixed and flaky tests are listed in a reply each.
+func prepareSlackThread(report *ginkgoTypes.Report, c *Options, runInfo *RunInfo) *slack_helper.Message {
+	header := getSummaryHeader(report, c)
+	msg := &slack_helper.Message{
+		Header:  header,
+		Context: fmt.Sprintf("Duration: %s", report.RunTime.Round(time.Second)),
+		Text:    header,
+	}
+
+	summary := ""
+	if runInfo.Regressions != nil {
+		newFailures, stillFailing := classifyFailures(report, runInfo.Regressions)
+		for _, failure := range newFailures {
+			reply := getSlackFailureReply(c, failure.specReport, runInfo.OpenIssues, fmt.Sprintf("failed in run %d", c.RunID))
+			reply.Broadcast = c.SlackInfo.BroadcastNewFailures
+			msg.Replies = append(msg.Replies, reply)
+		}
+		for _, failure := range stillFailing {
+			msg.Replies = append(msg.Replies, getSlackFailureReply(c, failure.specReport, runInfo.OpenIssues,
+				fmt.Sprintf("failing since run %d", failure.failingSince)))
+		}
+		if fixed := getSlackFixedSection(runInfo.Regressions); fixed != "" {
+			msg.Replies = append(msg.Replies, slack_helper.Reply{Sections: []string{fixed}, Text: "Fixed since last run"})
+		}
+		summary = fmt.Sprintf("%d new failures, %d still failing, %d fixed", len(newFailures), len(stillFailing),
+			len(runInfo.Regressions.Fixed))
+	} else {
+		failed := 0
+		for i := range report.SpecReports {
+			specReport := &report.SpecReports[i]
+			if specReport.Failed() {
+				msg.Replies = append(msg.Replies, getSlackFailureReply(c, specReport, runInfo.OpenIssues,
+					fmt.Sprintf("failed in run %d", c.RunID)))
+				failed++
+			}
+		}
+		summary = fmt.Sprintf("%d failed tests", failed)
+	}
+
+	if flaky := getSlackFlakySection(report, c); flaky != "" {
+		msg.Replies = append(msg.Replies, slack_helper.Reply{Sections: []string{flaky}, Text: "Flaky tests"})
+	}
+
+	if len(msg.Replies) == 0 {
+		msg.Sections = []string{":white_check_mark: No failed tests"}
+	} else {
+		msg.Sections = []string{fmt.Sprintf(":thread: %s. Details in thread", summary)}
+	}
+
+	msg.File = getSlackFailureLog(report, c)
+	return msg
+}
+
+// getSlackFailureReply returns the thread reply with the details of a failed test. Stack trace is
+// truncated keeping its beginning, GinkgoWriter output keeping its end.
+func getSlackFailureReply(c *Options, specReport *ginkgoTypes.SpecReport, openIssues []jira.Issue,
+	status string) slack_helper.Reply {
+	sections := []string{getSlackFailureSection(c, specReport, openIssues, status)}
+	if message := specReport.Failure.Message; message != "" {
+		sections = append(sections, getSlackCodeSection("Failure", utils.TruncateTail(message, maxSlackDetailLength)))
+	}
+	if stackTrace := specReport.Failure.Location.FullStackTrace; stackTrace != "" {
+		sections = append(sections, getSlackCodeSection("Stack trace", utils.TruncateTail(stackTrace, maxSlackDetailLength)))
+	}
+	if output := specReport.CapturedGinkgoWriterOutput; output != "" {
+		sections = append(sections, getSlackCodeSection("GinkgoWriter output", utils.TruncateHead(output, maxSlackDetailLength)))
+	}
+
+	return slack_helper.Reply{
+		Sections: sections,
+		Text:     fmt.Sprintf("Test %q %s", getTestText(specReport), status),
+	}
+}
+
+// getSlackCodeSection returns a mrkdwn section with title followed by text as code block.
+// Code fences in text are neutralized so they cannot end the code block.
+func getSlackCodeSection(title, text string) string {
+	return fmt.Sprintf("*%s*\n```%s```", title, slack_helper.EscapeCode(text))
+}
+
+// getSlackFlakySection returns the mrkdwn section listing flaky tests. Empty if there are none.
+func getSlackFlakySection(report *ginkgoTypes.Report, c *Options) string {
+	flaky := ""
+	for i := range report.SpecReports {
+		specReport := &report.SpecReports[i]
+		if !specReport.Failed() && ginkgo_helper.IsFlaky(specReport) {
+			flaky += fmt.Sprintf("\n:warning: %s passed in run %d after %d attempts",
+				slack_helper.Escape(getTestText(specReport)), c.RunID, specReport.NumAttempts)
+		}
+	}
+
+	if flaky == "" {
+		return ""
+	}
+	return "*Flaky tests*" + flaky
+}
+
+// getSlackFailureLog returns, if SlackInfo.UploadFailureLog is set, the text file with failure message,
+// full stack trace and GinkgoWriter output of all failed tests. Nil if disabled or no test failed.
+func getSlackFailureLog(report *ginkgoTypes.Report, c *Options) *slack_helper.File {
+	if c.SlackInfo == nil || !c.SlackInfo.UploadFailureLog {
+		return nil
+	}
+
+	var content strings.Builder
+	for i := range report.SpecReports {
+		specReport := &report.SpecReports[i]
+		if !specReport.Failed() {
+			continue
+		}
+		fmt.Fprintf(&content, "=== Test: %s\n", getTestText(specReport))
+		fmt.Fprintf(&content, "Location: %s\n\n", specReport.Failure.Location.String())
+		fmt.Fprintf(&content, "Failure:\n%s\n\n", specReport.Failure.Message)
+		fmt.Fprintf(&content, "Stack trace:\n%s\n\n", specReport.Failure.Location.FullStackTrace)
+		if specReport.CapturedGinkgoWriterOutput != "" {
+			fmt.Fprintf(&content, "GinkgoWriter output:\n%s\n\n", specReport.CapturedGinkgoWriterOutput)
+		}
+	}
+
+	if content.Len() == 0 {
+		return nil
+	}
+	return &slack_helper.File{
+		Name:    fmt.Sprintf("failures-run-%d.txt", c.RunID),
+		Title:   fmt.Sprintf("Failure log of run %d", c.RunID),
+		Content: []byte(content.String()),
+	}
+}
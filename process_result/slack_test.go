@@ -0,0 +1,165 @@
+package process_result_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	. "github.com/onsi/ginkgo/v2"
+	ginkgoTypes "github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/ginkgo_helper"
+	"github.com/gianlucam76/ginkgo-tracker-notifier/process_result"
+)
+
+var _ = Describe("PrepareSlackMessage", func() {
+	var c *process_result.Options
+	var report *ginkgoTypes.Report
+
+	BeforeEach(func() {
+		c = &process_result.Options{}
+		process_result.WithRunID(int64(31))(c)
+		process_result.WithJira(process_result.JiraInfo{BaseURL: "https://jira.org/"})(c)
+
+		specReports := getSpecReport()
+		specReports[2].Failure.Location = ginkgoTypes.CodeLocation{FileName: "/src/list_test.go", LineNumber: 42}
+		specReports = append(specReports, ginkgoTypes.SpecReport{
+			LeafNodeType: ginkgoTypes.NodeTypeIt,
+			State:        ginkgoTypes.SpecStatePassed,
+			NumAttempts:  2,
+			LeafNodeText: "return <sorted> list",
+		})
+		report = &ginkgoTypes.Report{SuiteDescription: "E2E", RunTime: 90 * time.Second, SpecReports: specReports}
+	})
+
+	It("builds header, a section per failed test and context", func() {
+		openIssues := []jira.Issue{{Key: "E2E-4", Fields: &jira.IssueFields{
+			Description: ginkgo_helper.GetDescription(&report.SpecReports[2])}}}
+		msg := process_result.PrepareSlackMessage(report, c, &process_result.RunInfo{RunID: 31, OpenIssues: openIssues})
+
+		Expect(msg.Header).To(Equal("E2E run 31: 3 failed, 1 passed, 1 flaky, 1 skipped"))
+		Expect(msg.Text).To(Equal(msg.Header))
+		Expect(msg.Context).To(Equal("Duration: 1m30s"))
+		Expect(msg.Sections).To(HaveLen(4))
+		Expect(msg.Sections[1]).To(Equal(":x: *Verify list methods return ordered list* failed in run 31\n" +
+			"`/src/list_test.go:42`\nJira: <https://jira.org/browse/E2E-4|E2E-4>"))
+		Expect(msg.Sections[3]).To(Equal("*Flaky tests*\n:warning: return &lt;sorted&gt; list passed in run 31 after 2 attempts"))
+	})
+
+	It("groups failed tests when regression detection is enabled", func() {
+		regressions := &process_result.Regressions{
+			NewFailures:  []process_result.RegressionTest{{Name: "gamma", Text: "gamma"}},
+			StillFailing: []process_result.RegressionTest{{Name: "alpha", Text: "alpha", FailingSince: 7}},
+			Fixed:        []process_result.RegressionTest{{Name: "beta", Text: "beta"}},
+		}
+		msg := process_result.PrepareSlackMessage(getRegressionReport("alpha", "gamma"), c,
+			&process_result.RunInfo{RunID: 31, Regressions: regressions})
+
+		Expect(msg.Header).To(Equal("Run 31: 2 failed, 1 passed"))
+		Expect(msg.Sections).To(HaveLen(5))
+		Expect(msg.Sections[0]).To(Equal("*New failures*"))
+		Expect(msg.Sections[1]).To(HavePrefix(":x: *gamma* failed in run 31"))
+		Expect(msg.Sections[2]).To(Equal("*Still failing*"))
+		Expect(msg.Sections[3]).To(HavePrefix(":x: *alpha* failing since run 7"))
+		Expect(msg.Sections[4]).To(Equal("*Fixed since last run*\n:white_check_mark: beta"))
+	})
+
+	It("reports runs with no failed tests", func() {
+		msg := process_result.PrepareSlackMessage(getRegressionReport(), c, &process_result.RunInfo{RunID: 31})
+		Expect(msg.Header).To(Equal("Run 31: 0 failed, 3 passed"))
+		Expect(msg.Sections).To(ConsistOf(ContainSubstring("No failed tests")))
+	})
+
+	It("posts details of failed tests as thread replies when threaded", func() {
+		process_result.WithSlack(process_result.SlackInfo{Threaded: true})(c)
+		report.SpecReports[2].Failure.Message = "Expected <int>: 1 to equal 2"
+		report.SpecReports[2].Failure.Location.FullStackTrace = strings.Repeat("frame\n", 1000)
+		report.SpecReports[2].CapturedGinkgoWriterOutput = "creating list"
+		msg := process_result.PrepareSlackMessage(report, c, &process_result.RunInfo{RunID: 31})
+
+		Expect(msg.Sections).To(Equal([]string{":thread: 3 failed tests. Details in thread"}))
+		Expect(msg.Replies).To(HaveLen(4))
+		reply := msg.Replies[1]
+		Expect(reply.Text).To(Equal(`Test "Verify list methods return ordered list" failed in run 31`))
+		Expect(reply.Broadcast).To(BeFalse())
+		Expect(reply.Sections).To(HaveLen(4))
+		Expect(reply.Sections[0]).To(HavePrefix(":x: *Verify list methods return ordered list* failed in run 31"))
+		Expect(reply.Sections[1]).To(Equal("*Failure*\n```Expected &lt;int&gt;: 1 to equal 2```"))
+		Expect(reply.Sections[2]).To(HavePrefix("*Stack trace*\n```frame\n"))
+		Expect(reply.Sections[2]).To(HaveSuffix("...[truncated]```"))
+		Expect(len(reply.Sections[2])).To(BeNumerically("<", 3000))
+		Expect(reply.Sections[3]).To(Equal("*GinkgoWriter output*\n```creating list```"))
+		Expect(msg.Replies[3].Sections).To(ConsistOf(HavePrefix("*Flaky tests*")))
+	})
+
+	It("neutralizes code fences in failure details", func() {
+		process_result.WithSlack(process_result.SlackInfo{Threaded: true})(c)
+		report.SpecReports[2].Failure.Message = "Expected\n```\nyaml\n```"
+		msg := process_result.PrepareSlackMessage(report, c, &process_result.RunInfo{RunID: 31})
+
+		failure := msg.Replies[1].Sections[1]
+		Expect(failure).To(HavePrefix("*Failure*\n```Expected"))
+		Expect(strings.Count(failure, "```")).To(Equal(2))
+	})
+
+	It("broadcasts only new failures when threaded", func() {
+		process_result.WithSlack(process_result.SlackInfo{Threaded: true, BroadcastNewFailures: true})(c)
+		regressions := &process_result.Regressions{
+			NewFailures:  []process_result.RegressionTest{{Name: "gamma", Text: "gamma"}},
+			StillFailing: []process_result.RegressionTest{{Name: "alpha", Text: "alpha", FailingSince: 7}},
+			Fixed:        []process_result.RegressionTest{{Name: "beta", Text: "beta"}},
+		}
+		msg := process_result.PrepareSlackMessage(getRegressionReport("alpha", "gamma"), c,
+			&process_result.RunInfo{RunID: 31, Regressions: regressions})
+
+		Expect(msg.Sections).To(Equal([]string{":thread: 1 new failures, 1 still failing, 1 fixed. Details in thread"}))
+		Expect(msg.Replies).To(HaveLen(3))
+		Expect(msg.Replies[0].Text).To(Equal(`Test "gamma" failed in run 31`))
+		Expect(msg.Replies[0].Broadcast).To(BeTrue())
+		Expect(msg.Replies[1].Text).To(Equal(`Test "alpha" failing since run 7`))
+		Expect(msg.Replies[1].Broadcast).To(BeFalse())
+		Expect(msg.Replies[2].Sections).To(Equal([]string{"*Fixed since last run*\n:white_check_mark: beta"}))
+		Expect(msg.Replies[2].Broadcast).To(BeFalse())
+	})
+
+	It("attaches a failure log when enabled", func() {
+		Expect(process_result.PrepareSlackMessage(report, c, &process_result.RunInfo{RunID: 31}).File).To(BeNil())
+
+		process_result.WithSlack(process_result.SlackInfo{UploadFailureLog: true})(c)
+		report.SpecReports[2].Failure.Message = "Expected <int>: 1 to equal 2"
+		report.SpecReports[2].Failure.Location.FullStackTrace = "frame"
+		report.SpecReports[2].CapturedGinkgoWriterOutput = "creating list"
+		msg := process_result.PrepareSlackMessage(report, c, &process_result.RunInfo{RunID: 31})
+
+		Expect(msg.File).ToNot(BeNil())
+		Expect(msg.File.Name).To(Equal("failures-run-31.txt"))
+		Expect(msg.File.Title).To(Equal("Failure log of run 31"))
+		Expect(string(msg.File.Content)).To(ContainSubstring("=== Test: Verify list methods return ordered list\n" +
+			"Location: /src/list_test.go:42\n\nFailure:\nExpected <int>: 1 to equal 2\n\nStack trace:\nframe\n\n" +
+			"GinkgoWriter output:\ncreating list\n\n"))
+		Expect(strings.Count(string(msg.File.Content), "=== Test: ")).To(Equal(3))
+
+		process_result.WithSlack(process_result.SlackInfo{UploadFailureLog: true, Threaded: true})(c)
+		Expect(process_result.PrepareSlackMessage(report, c, &process_result.RunInfo{RunID: 31}).File).ToNot(BeNil())
+	})
+
+	It("attaches no failure log when no test failed", func() {
+		process_result.WithSlack(process_result.SlackInfo{UploadFailureLog: true})(c)
+		msg := process_result.PrepareSlackMessage(getRegressionReport(), c, &process_result.RunInfo{RunID: 31})
+		Expect(msg.File).To(BeNil())
+	})
+})
+
+var _ = Describe("VerifySlackInfo", func() {
+	It("reports error when failure log is uploaded to a webhook", func() {
+		c := &process_result.Options{}
+		process_result.WithSlack(process_result.SlackInfo{
+			WebhookURL:       "https://hooks.slack.com/services/T0/B0/XYZ",
+			UploadFailureLog: true,
+		})(c)
+		Expect(process_result.VerifySlackInfo(context.TODO(), c)).To(MatchError(
+			"failed to verify slack info. Error: failure log cannot be uploaded to an incoming webhook"))
+	})
+})
@@ -0,0 +1,65 @@
+// Package query reads test results stored in elastic by process_result.
+package query
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/internal/elastic_helper"
+)
+
+// ElasticInfo contains the elastic DB configuration. It is the same type as
+// process_result.ElasticInfo.
+type ElasticInfo = elastic_helper.ElasticInfo
+
+// Result is the result of a test in a run, as stored in elastic.
+// Failed and Passed (at first attempt or after failing, see Flaky) report its outcome.
+type Result = elastic_helper.ElasticResult
+
+// TestHistory is the history of a test across a window of runs. Results are sorted
+// most recent run first.
+// FailureStreak, FirstFailingRun, PassStreak and PassRate summarize the history.
+type TestHistory = elastic_helper.TestHistory
+
+// GetTestHistory returns the history of test testName across the last lastRuns runs.
+// Runs are ordered by run id, which is expected to increase with every run.
+func GetTestHistory(ctx context.Context, info ElasticInfo, testName string,
+	lastRuns int) (*TestHistory, error) {
+	if testName == "" {
+		return nil, fmt.Errorf("test name must be set")
+	}
+
+	histories, err := getHistories(ctx, &info, []string{testName}, lastRuns)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(histories) == 0 {
+		return &TestHistory{Name: testName, Results: []Result{}}, nil
+	}
+	return histories[0], nil
+}
+
+// GetHistory returns the history of every test across the last lastRuns runs,
+// sorted by test name.
+// Runs are ordered by run id, which is expected to increase with every run.
+func GetHistory(ctx context.Context, info ElasticInfo, lastRuns int) ([]*TestHistory, error) {
+	return getHistories(ctx, &info, nil, lastRuns)
+}
+
+// GetFailuresForRun returns all tests which failed in run runID, sorted by test name.
+func GetFailuresForRun(ctx context.Context, info ElasticInfo, runID int64) ([]Result, error) {
+	return elastic_helper.GetFailuresForRun(ctx, &info, runID)
+}
+
+// getHistories returns the history, across the last lastRuns runs, of testNames or,
+// if testNames is empty, of every test
+func getHistories(ctx context.Context, info *ElasticInfo, testNames []string,
+	lastRuns int) ([]*TestHistory, error) {
+	runs, err := elastic_helper.GetLastRuns(ctx, info, lastRuns)
+	if err != nil {
+		return nil, err
+	}
+
+	return elastic_helper.GetHistories(ctx, info, runs, testNames)
+}
@@ -0,0 +1,31 @@
+package query_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/gianlucam76/ginkgo-tracker-notifier/query"
+)
+
+var _ = Describe("Query", func() {
+	It("GetTestHistory requires a test name", func() {
+		info := query.ElasticInfo{URL: "http://127.0.0.1:9200", Index: "e2e"}
+		_, err := query.GetTestHistory(context.TODO(), info, "", 10)
+		Expect(err).To(MatchError("test name must be set"))
+	})
+
+	It("TestHistory summarizes results, most recent run first", func() {
+		history := &query.TestHistory{Name: "test", Results: []query.Result{
+			{Name: "test", Run: 3, Result: "failed"},
+			{Name: "test", Run: 2, Result: "failed"},
+			{Name: "test", Run: 1, Result: "passed", Flaky: true},
+		}}
+		Expect(history.FailureStreak()).To(Equal(2))
+		run, ok := history.FirstFailingRun()
+		Expect(ok).To(BeTrue())
+		Expect(run).To(BeEquivalentTo(2))
+		Expect(history.Results[2].Passed()).To(BeTrue())
+	})
+})